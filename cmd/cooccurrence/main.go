@@ -0,0 +1,53 @@
+// Command cooccurrence rebuilds the "also bought" item similarities from the event log.
+// It is meant to run periodically, e.g. from a nightly cron job.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"backend-challenge/internal/adapters/persistence/repository"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/infrastructure/config"
+	"backend-challenge/internal/infrastructure/db"
+)
+
+func main() {
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config.json"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	mongoClient, err := db.ConnectMongoDB(cfg.MongoURI)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	defer func() {
+		if err := mongoClient.Disconnect(context.TODO()); err != nil {
+			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+		}
+	}()
+
+	eventRepo := repository.NewEventRepository(mongoClient, cfg.Database, "events")
+
+	itemSimilarityRepo := repository.NewItemSimilarityRepository(mongoClient, cfg.Database, "item_similarities")
+
+	coOccurrenceService := services.NewCoOccurrenceService(eventRepo, itemSimilarityRepo, cfg.CoOccurrence)
+
+	startTime := time.Now()
+
+	products, err := coOccurrenceService.Rebuild()
+	if err != nil {
+		log.Fatalf("Failed to rebuild item similarities: %v", err)
+	}
+
+	log.Printf("Rebuilt item similarities of %d products in %s", products, time.Since(startTime))
+}
@@ -0,0 +1,67 @@
+// Command embeddings trains the dense product embedding model on the catalog and stores it,
+// for the "embedding" recommendation strategy. Run it after large catalog changes; the server
+// trains one itself on start when none is stored.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"backend-challenge/internal/adapters/persistence/repository"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/infrastructure/config"
+	"backend-challenge/internal/infrastructure/db"
+)
+
+func main() {
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config.json"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	mongoClient, err := db.ConnectMongoDB(cfg.MongoURI)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	defer func() {
+		if err := mongoClient.Disconnect(context.TODO()); err != nil {
+			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+		}
+	}()
+
+	productRepo := repository.NewProductRepository(mongoClient, cfg.Database, "products")
+
+	productVectorRepo := repository.NewProductVectorRepository(mongoClient, cfg.Database, "product_vectors")
+
+	termEmbeddingRepo := repository.NewTermEmbeddingRepository(mongoClient, cfg.Database, "term_embeddings")
+
+	vectorStore := services.NewVectorStore(productRepo, productVectorRepo, services.NewRecommendationService(cfg.Recommendation))
+
+	// Loading brings the stored product vectors up to date with the catalog
+	if err := vectorStore.Load(); err != nil {
+		log.Fatalf("Failed to load product vectors: %v", err)
+	}
+
+	vectors, err := productVectorRepo.GetAll()
+	if err != nil {
+		log.Fatalf("Failed to read product vectors: %v", err)
+	}
+
+	startTime := time.Now()
+
+	model := services.TrainEmbeddingModel(vectors, cfg.Embedding)
+
+	if err := termEmbeddingRepo.ReplaceAll(model.Terms()); err != nil {
+		log.Fatalf("Failed to store embedding model: %v", err)
+	}
+
+	log.Printf("Trained embeddings of %d terms in %s", len(model.Terms()), time.Since(startTime))
+}
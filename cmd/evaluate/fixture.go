@@ -0,0 +1,139 @@
+package main
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fixtureProduct is a product as exported from the store's MongoDB, in extended JSON,
+// where categories are embedded documents instead of names
+type fixtureProduct struct {
+	ID          primitive.ObjectID   `bson:"_id"`
+	StoreID     string               `bson:"storeId"`
+	Categories  []fixtureCategory    `bson:"categories"`
+	Description entities.Description `bson:"description"`
+	Name        entities.Name        `bson:"name"`
+	Published   bool                 `bson:"published"`
+	Variants    []entities.Variant   `bson:"variants"`
+	SoldCount   int                  `bson:"soldCount"`
+	ClickCount  int                  `bson:"clickCount"`
+	CreatedAt   time.Time            `bson:"createdAt"`
+	UpdatedAt   time.Time            `bson:"updatedAt"`
+}
+
+type fixtureCategory struct {
+	ID            string                   `bson:"id"`
+	Name          entities.LocalizedString `bson:"name"`
+	Subcategories []string                 `bson:"subcategories"`
+}
+
+// loadProducts reads a product fixture like products.json, along with the category hierarchy embedded in it
+func loadProducts(path string) ([]*entities.Product, []*entities.Category, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Extended JSON can only be decoded into a document, so wrap the array in one
+	var fixture struct {
+		Products []fixtureProduct `bson:"products"`
+	}
+
+	wrapped := append(append([]byte(`{"products":`), data...), '}')
+
+	if err := bson.UnmarshalExtJSON(wrapped, false, &fixture); err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	products := make([]*entities.Product, 0, len(fixture.Products))
+	embedded := make(map[string]fixtureCategory)
+
+	for _, p := range fixture.Products {
+		categories := make([]string, 0, len(p.Categories))
+
+		for _, category := range p.Categories {
+			categories = append(categories, categoryName(category))
+			embedded[category.ID] = category
+		}
+
+		products = append(products, &entities.Product{
+			ID:          p.ID,
+			StoreID:     p.StoreID,
+			Categories:  categories,
+			Description: p.Description,
+			Name:        p.Name,
+			Published:   p.Published,
+			Variants:    p.Variants,
+			SoldCount:   p.SoldCount,
+			ClickCount:  p.ClickCount,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+		})
+	}
+
+	return products, fixtureCategories(embedded), nil
+}
+
+// fixtureCategories turns the categories embedded in the products into the hierarchy stored by the server,
+// keeping their store platform IDs, which subcategories reference. Subcategories no product is in are left out.
+func fixtureCategories(embedded map[string]fixtureCategory) []*entities.Category {
+	ids := make([]string, 0, len(embedded))
+
+	for id := range embedded {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	categories := make([]*entities.Category, 0, len(ids))
+
+	for _, id := range ids {
+		category := &entities.Category{ID: primitive.NewObjectID(), ExternalID: id, Name: categoryName(embedded[id])}
+
+		for _, subcategory := range embedded[id].Subcategories {
+			if _, ok := embedded[subcategory]; ok {
+				category.Subcategories = append(category.Subcategories, subcategory)
+			}
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories
+}
+
+// categoryName picks the first localized name of a category, or its id when it has none
+func categoryName(category fixtureCategory) string {
+	for _, name := range []*string{category.Name.Es, category.Name.En, category.Name.Pt} {
+		if name != nil && *name != "" {
+			return *name
+		}
+	}
+
+	return category.ID
+}
+
+// loadEvents reads a JSON array of interaction events, in the format accepted by POST /v1/events
+func loadEvents(path string) ([]*entities.Event, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*entities.Event
+
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return events, nil
+}
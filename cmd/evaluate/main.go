@@ -0,0 +1,108 @@
+// Command evaluate measures the recommendation strategies offline. It loads a product fixture and
+// held out interactions, asks every strategy for the products that went with each interaction,
+// and prints a JSON report of precision@k, recall@k, NDCG, catalog coverage and diversity
+// that can be diffed between commits.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/infrastructure/config"
+)
+
+func main() {
+	productsPath := flag.String("products", "products.json", "product fixture, as exported from MongoDB")
+	interactionsPath := flag.String("interactions", "", "held out interactions to predict, a JSON array of events (required)")
+	trainPath := flag.String("train", "", "interactions the behavioral strategies learn from, a JSON array of events")
+	k := flag.Int("k", 10, "number of recommendations evaluated per query")
+	outPath := flag.String("out", "", "file to write the report to, standard output when empty")
+	flag.Parse()
+
+	if *interactionsPath == "" || *k < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config.json"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if err := cfg.Recommendation.Validate(); err != nil {
+		log.Fatalf("Invalid recommendation config: %v", err)
+	}
+
+	products, categories, err := loadProducts(*productsPath)
+	if err != nil {
+		log.Fatalf("Failed to load products: %v", err)
+	}
+
+	heldOut, err := loadEvents(*interactionsPath)
+	if err != nil {
+		log.Fatalf("Failed to load interactions: %v", err)
+	}
+
+	train := []*entities.Event{}
+
+	if *trainPath != "" {
+		if train, err = loadEvents(*trainPath); err != nil {
+			log.Fatalf("Failed to load training interactions: %v", err)
+		}
+	}
+
+	productRepo := memory.NewProductRepository(products)
+
+	recommendationService := services.NewRecommendationService(cfg.Recommendation)
+	recommendationService.SetCategoryTree(services.NewCategoryTree(categories))
+
+	vectorStore := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), recommendationService)
+
+	itemSimilarityRepo := memory.NewItemSimilarityRepository()
+
+	if err := itemSimilarityRepo.ReplaceAll(services.BuildItemSimilarities(train, cfg.CoOccurrence)); err != nil {
+		log.Fatalf("Failed to build item similarities: %v", err)
+	}
+
+	popularityService := services.NewPopularityService(memory.NewEventRepository(train), cfg.Popularity)
+
+	// The embedding model is trained on the fixture, like the server does without a stored one
+	embeddingIndex := services.NewEmbeddingIndex(memory.NewTermEmbeddingRepository(), vectorStore, cfg.Embedding)
+
+	recommenders := map[string]services.Recommender{
+		services.StrategyContent:   services.NewContentRecommender(vectorStore, recommendationService),
+		services.StrategyHybrid:    services.NewHybridRecommender(vectorStore, recommendationService, itemSimilarityRepo, popularityService, cfg.Hybrid),
+		services.StrategyEmbedding: services.NewEmbeddingRecommender(embeddingIndex, vectorStore, recommendationService),
+	}
+
+	report, err := services.NewEvaluator(vectorStore, recommendationService, recommenders).Evaluate(services.EvaluationQueries(heldOut), *k)
+	if err != nil {
+		log.Fatalf("Failed to evaluate: %v", err)
+	}
+
+	out := os.Stdout
+
+	if *outPath != "" {
+		if out, err = os.Create(*outPath); err != nil {
+			log.Fatalf("Failed to create report: %v", err)
+		}
+		defer out.Close()
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"backend-challenge/internal/adapters/persistence/repository"
 	"backend-challenge/internal/adapters/web/handlers"
 	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/infrastructure/config"
 	"backend-challenge/internal/infrastructure/db"
 
 	"github.com/gin-gonic/gin"
@@ -14,14 +16,37 @@ import (
 
 var (
 	productService services.ProductService
+	trendingService services.TrendingService
+	sessionRecommendationService services.SessionRecommendationService
+	batchRecommendationService services.BatchRecommendationService
+	experimentService *services.ExperimentService
+	recommendationCache *services.RecommendationCache
+	merchandisingService services.MerchandisingService
 	categoryService services.CategoryService
-	// brainService   *services.BrainService
+	eventService   services.EventService
+	brainService   *services.BrainService
 )
 
 func main() {
-	mongoURI := "mongodb://localhost:27017"
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config.json"
+	}
 
-	mongoClient, err := db.ConnectMongoDB(mongoURI)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if err := cfg.Experiment.Validate(); err != nil {
+		log.Fatalf("Invalid experiment config: %v", err)
+	}
+
+	if err := cfg.Recommendation.Validate(); err != nil {
+		log.Fatalf("Invalid recommendation config: %v", err)
+	}
+
+	mongoClient, err := db.ConnectMongoDB(cfg.MongoURI)
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
@@ -32,35 +57,103 @@ func main() {
 		}
 	}()
 
-	productRepo := repository.NewProductRepository(mongoClient, "backend-challenge", "products")
+	productRepo := repository.NewProductRepository(mongoClient, cfg.Database, "products")
 
-	recommendationService := services.NewRecommendationService()
+	productVectorRepo := repository.NewProductVectorRepository(mongoClient, cfg.Database, "product_vectors")
 
-	productService = services.NewProductService(productRepo, recommendationService)
+	recommendationService := services.NewRecommendationService(cfg.Recommendation)
+
+	vectorStore := services.NewVectorStore(productRepo, productVectorRepo, recommendationService)
+
+	if err := vectorStore.Load(); err != nil {
+		log.Fatalf("Failed to load product vectors: %v", err)
+	}
 
-	categoryRepo := repository.NewCategoryRepository(mongoClient, "backend-challenge", "categories")
-	
-	categoryService  = services.NewCategoryService(categoryRepo)
-	
 	// Amount of product recommendations
-	// brainService = services.NewBrainService(15)
+	brainService = services.NewBrainService(15)
+
+	itemSimilarityRepo := repository.NewItemSimilarityRepository(mongoClient, cfg.Database, "item_similarities")
+
+	eventRepo := repository.NewEventRepository(mongoClient, cfg.Database, "events")
+
+	popularityService := services.NewPopularityService(eventRepo, cfg.Popularity)
+
+	stopRefreshing := popularityService.StartRefreshing()
+	defer stopRefreshing()
+
+	trendingService = services.NewTrendingService(productRepo, popularityService)
+
+	termEmbeddingRepo := repository.NewTermEmbeddingRepository(mongoClient, cfg.Database, "term_embeddings")
+
+	embeddingIndex := services.NewEmbeddingIndex(termEmbeddingRepo, vectorStore, cfg.Embedding)
+
+	if err := embeddingIndex.Load(); err != nil {
+		log.Fatalf("Failed to load product embeddings: %v", err)
+	}
+
+	recommenders := map[string]services.Recommender{
+		services.StrategyContent:   services.NewContentRecommender(vectorStore, recommendationService),
+		services.StrategyHybrid:    services.NewHybridRecommender(vectorStore, recommendationService, itemSimilarityRepo, popularityService, cfg.Hybrid),
+		services.StrategyEmbedding: services.NewEmbeddingRecommender(embeddingIndex, vectorStore, recommendationService),
+	}
+
+	recommendationCache = services.NewRecommendationCache(cfg.Cache)
+
+	merchandisingRuleRepo := repository.NewMerchandisingRuleRepository(mongoClient, cfg.Database, "merchandising_rules")
 
+	merchandisingService = services.NewMerchandisingService(merchandisingRuleRepo, vectorStore, recommendationService)
 
+	// Cached recommendations may have been shaped by the rules that changed
+	merchandisingService.Watch(recommendationCache.Clear)
 
-	InitRoutes()
+	if err := merchandisingService.Load(); err != nil {
+		log.Fatalf("Failed to load merchandising rules: %v", err)
+	}
+
+	productService = services.NewCachedProductService(services.NewProductService(productRepo, itemSimilarityRepo, vectorStore, recommenders, brainService, merchandisingService), recommendationCache)
+
+	categoryRepo := repository.NewCategoryRepository(mongoClient, cfg.Database, "categories")
+	
+	categoryService  = services.NewCategoryService(categoryRepo, recommendationService)
+
+	if err := categoryService.LoadCategoryTree(); err != nil {
+		log.Fatalf("Failed to load category tree: %v", err)
+	}
+
+	// Cached recommendations may have given credit for categories that moved
+	categoryService.Watch(recommendationCache.Clear)
+
+	impressionRepo := repository.NewImpressionRepository(mongoClient, cfg.Database, "impressions")
+
+	experimentService = services.NewExperimentService(cfg.Experiment, impressionRepo, eventRepo)
+
+	eventService = services.NewEventService(eventRepo, productRepo, vectorStore, experimentService)
+
+	sessionRecommendationService = services.NewSessionRecommendationService(eventRepo, productRepo, vectorStore, recommendationService)
+
+	batchRecommendationService = services.NewBatchRecommendationService(productRepo, vectorStore, recommendationService)
+
+	InitRoutes(cfg.AdminToken)
 }
 
-func InitRoutes() {
+func InitRoutes(adminToken string) {
 	router := gin.Default()
 
+	// Requests bearing the admin token may also see unpublished products
+	router.Use(handlers.AdminScope(adminToken))
+
 	v1 := router.Group("/v1")
 
-	productHandler := handlers.NewProductHandler(productService, /*brainService*/)
+	productHandler := handlers.NewProductHandler(productService, brainService, experimentService)
+
+	trendingHandler := handlers.NewTrendingHandler(trendingService)
 
 	v1.POST("/products", productHandler.CreateProduct)
 	v1.GET("/products", productHandler.GetAllProducts)
+	v1.GET("/products/trending", trendingHandler.GetTrending)
 	v1.GET("/products/:id", productHandler.GetProductByID)
 	v1.GET("/products/:id/recommendations", productHandler.GetRecommendations)
+	v1.GET("/products/:id/also-bought", productHandler.GetAlsoBought)
 	v1.PUT("/products/:id", productHandler.UpdateProduct)
 	v1.DELETE("products/:id", productHandler.DeleteProduct)
 
@@ -72,5 +165,30 @@ func InitRoutes() {
 	v1.PUT("/categories/:id", categoryHandler.UpdateCategory)
 	v1.DELETE("/categories/:id", categoryHandler.DeleteCategory)
 
+	eventHandler := handlers.NewEventHandler(eventService)
+
+	v1.POST("/events", eventHandler.RecordEvents)
+
+	recommendationHandler := handlers.NewRecommendationHandler(sessionRecommendationService, batchRecommendationService)
+
+	v1.GET("/recommendations", recommendationHandler.GetSessionRecommendations)
+	v1.POST("/recommendations/batch", recommendationHandler.GetBatchRecommendations)
+
+	experimentHandler := handlers.NewExperimentHandler(experimentService)
+
+	v1.GET("/experiments/report", experimentHandler.GetReport)
+
+	merchandisingRuleHandler := handlers.NewMerchandisingRuleHandler(merchandisingService)
+
+	v1.POST("/merchandising-rules", merchandisingRuleHandler.CreateRule)
+	v1.GET("/merchandising-rules", merchandisingRuleHandler.GetAllRules)
+	v1.GET("/merchandising-rules/:id", merchandisingRuleHandler.GetRuleByID)
+	v1.PUT("/merchandising-rules/:id", merchandisingRuleHandler.UpdateRule)
+	v1.DELETE("/merchandising-rules/:id", merchandisingRuleHandler.DeleteRule)
+
+	metricsHandler := handlers.NewMetricsHandler(recommendationCache)
+
+	v1.GET("/metrics", metricsHandler.GetMetrics)
+
 	router.Run(":8080")
 }
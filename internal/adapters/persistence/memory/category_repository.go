@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type categoryRepository struct {
+	mu         sync.RWMutex
+	categories []*entities.Category
+}
+
+func NewCategoryRepository(categories []*entities.Category) repositories.CategoryRepository {
+	return &categoryRepository{categories: append([]*entities.Category{}, categories...)}
+}
+
+func (r *categoryRepository) GetAll() ([]*entities.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*entities.Category{}, r.categories...), nil
+}
+
+func (r *categoryRepository) GetByID(id string) (*entities.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return r.categories[i], nil
+	}
+
+	return nil, fmt.Errorf("category %s not found", id)
+}
+
+func (r *categoryRepository) Create(category *entities.Category) error {
+	category.ID = primitive.NewObjectID()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.categories = append(r.categories, category)
+
+	return nil
+}
+
+func (r *categoryRepository) Update(category *entities.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.indexOf(category.ID.Hex())
+
+	if i < 0 {
+		return fmt.Errorf("category %s not found", category.ID.Hex())
+	}
+
+	r.categories[i] = category
+
+	return nil
+}
+
+func (r *categoryRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		r.categories = append(r.categories[:i], r.categories[i+1:]...)
+	}
+
+	return nil
+}
+
+func (r *categoryRepository) indexOf(id string) int {
+	for i, category := range r.categories {
+		if category.ID.Hex() == id {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type eventRepository struct {
+	mu       sync.RWMutex
+	events   []*entities.Event // sorted by timestamp
+	eventIDs map[string]bool   // client event IDs already stored
+}
+
+// NewEventRepository returns an in-memory event repository holding the given events
+func NewEventRepository(events []*entities.Event) repositories.EventRepository {
+	r := &eventRepository{eventIDs: make(map[string]bool)}
+	r.insert(events)
+
+	return r
+}
+
+// InsertMany stores the events whose client event ID isn't stored yet, and returns them
+func (r *eventRepository) InsertMany(events []*entities.Event) ([]*entities.Event, error) {
+	for _, event := range events {
+		if event.ID.IsZero() {
+			event.ID = primitive.NewObjectID()
+		}
+	}
+
+	return r.insert(events), nil
+}
+
+func (r *eventRepository) GetByTypes(types []entities.EventType) ([]*entities.Event, error) {
+	return r.filter(func(event *entities.Event) bool {
+		return slices.Contains(types, event.Type)
+	}), nil
+}
+
+func (r *eventRepository) GetSince(since time.Time) ([]*entities.Event, error) {
+	return r.filter(func(event *entities.Event) bool {
+		return !event.Timestamp.Before(since)
+	}), nil
+}
+
+func (r *eventRepository) GetReceivedSince(since time.Time) ([]*entities.Event, error) {
+	return r.filter(func(event *entities.Event) bool {
+		return !event.ReceivedAt.Before(since)
+	}), nil
+}
+
+// GetBySession returns a session's latest events, newest first
+func (r *eventRepository) GetBySession(sessionID string, limit int) ([]*entities.Event, error) {
+	events := r.filter(func(event *entities.Event) bool {
+		return event.SessionID == sessionID
+	})
+
+	slices.Reverse(events)
+
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+func (r *eventRepository) GetByExperiment(experiment string, types []entities.EventType) ([]*entities.Event, error) {
+	return r.filter(func(event *entities.Event) bool {
+		return event.Experiment == experiment && slices.Contains(types, event.Type)
+	}), nil
+}
+
+func (r *eventRepository) insert(events []*entities.Event) []*entities.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inserted := make([]*entities.Event, 0, len(events))
+
+	for _, event := range events {
+		if event.EventID != "" {
+			if r.eventIDs[event.EventID] {
+				continue
+			}
+
+			r.eventIDs[event.EventID] = true
+		}
+
+		inserted = append(inserted, event)
+	}
+
+	r.events = append(r.events, inserted...)
+
+	sort.SliceStable(r.events, func(i, j int) bool {
+		return r.events[i].Timestamp.Before(r.events[j].Timestamp)
+	})
+
+	return inserted
+}
+
+// filter returns the matching events, oldest first
+func (r *eventRepository) filter(matches func(event *entities.Event) bool) []*entities.Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := []*entities.Event{}
+
+	for _, event := range r.events {
+		if matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
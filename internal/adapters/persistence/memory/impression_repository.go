@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type impressionRepository struct {
+	mu          sync.RWMutex
+	impressions []*entities.Impression
+}
+
+func NewImpressionRepository() repositories.ImpressionRepository {
+	return &impressionRepository{}
+}
+
+func (r *impressionRepository) Insert(impression *entities.Impression) error {
+	impression.ID = primitive.NewObjectID()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.impressions = append(r.impressions, impression)
+
+	return nil
+}
+
+func (r *impressionRepository) GetByExperiment(experiment string) ([]*entities.Impression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	impressions := []*entities.Impression{}
+
+	for _, impression := range r.impressions {
+		if impression.Experiment == experiment {
+			impressions = append(impressions, impression)
+		}
+	}
+
+	return impressions, nil
+}
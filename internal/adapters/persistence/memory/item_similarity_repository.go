@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"sync"
+)
+
+type itemSimilarityRepository struct {
+	mu           sync.RWMutex
+	similarities map[string]*entities.ItemSimilarity
+}
+
+func NewItemSimilarityRepository() repositories.ItemSimilarityRepository {
+	return &itemSimilarityRepository{similarities: make(map[string]*entities.ItemSimilarity)}
+}
+
+// GetByProductID returns the similarities of a product, or nil when it has none
+func (r *itemSimilarityRepository) GetByProductID(productID string) (*entities.ItemSimilarity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.similarities[productID], nil
+}
+
+func (r *itemSimilarityRepository) ReplaceAll(similarities []*entities.ItemSimilarity) error {
+	replaced := make(map[string]*entities.ItemSimilarity, len(similarities))
+
+	for _, similarity := range similarities {
+		replaced[similarity.ProductID] = similarity
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.similarities = replaced
+
+	return nil
+}
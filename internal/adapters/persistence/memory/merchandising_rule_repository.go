@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type merchandisingRuleRepository struct {
+	mu    sync.RWMutex
+	rules []*entities.MerchandisingRule
+}
+
+func NewMerchandisingRuleRepository() repositories.MerchandisingRuleRepository {
+	return &merchandisingRuleRepository{}
+}
+
+func (r *merchandisingRuleRepository) GetAll() ([]*entities.MerchandisingRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*entities.MerchandisingRule{}, r.rules...), nil
+}
+
+func (r *merchandisingRuleRepository) GetByID(id string) (*entities.MerchandisingRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		return r.rules[i], nil
+	}
+
+	return nil, fmt.Errorf("merchandising rule %s not found", id)
+}
+
+func (r *merchandisingRuleRepository) Create(rule *entities.MerchandisingRule) error {
+	rule.ID = primitive.NewObjectID()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules = append(r.rules, rule)
+
+	return nil
+}
+
+func (r *merchandisingRuleRepository) Update(rule *entities.MerchandisingRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.indexOf(rule.ID.Hex())
+
+	if i < 0 {
+		return fmt.Errorf("merchandising rule %s not found", rule.ID.Hex())
+	}
+
+	r.rules[i] = rule
+
+	return nil
+}
+
+func (r *merchandisingRuleRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.indexOf(id); i >= 0 {
+		r.rules = append(r.rules[:i], r.rules[i+1:]...)
+	}
+
+	return nil
+}
+
+func (r *merchandisingRuleRepository) indexOf(id string) int {
+	for i, rule := range r.rules {
+		if rule.ID.Hex() == id {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,162 @@
+// Package memory implements the repositories in memory, for tools that work on a fixture instead of the database
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type productRepository struct {
+	mu       sync.RWMutex
+	ids      []string // insertion order, which listings follow
+	products map[string]*entities.Product
+}
+
+// NewProductRepository returns an in-memory product repository holding the given products
+func NewProductRepository(products []*entities.Product) repositories.ProductRepository {
+	r := &productRepository{products: make(map[string]*entities.Product, len(products))}
+
+	for _, product := range products {
+		r.put(product)
+	}
+
+	return r
+}
+
+func (r *productRepository) GetByID(id string) (*entities.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[id]
+
+	if !ok {
+		return nil, fmt.Errorf("product %s not found", id)
+	}
+
+	return product, nil
+}
+
+// GetByIDs fetches the given products, returned in the same order as ids. Unknown ids, and unpublished ones
+// unless the query includes them, are skipped.
+func (r *productRepository) GetByIDs(ids []string, query repositories.ProductQuery) ([]*entities.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]*entities.Product, 0, len(ids))
+
+	for _, id := range ids {
+		if product, ok := r.products[id]; ok && visible(product, query) {
+			products = append(products, product)
+		}
+	}
+
+	return products, nil
+}
+
+func (r *productRepository) GetPaginated(offset, limit int, query repositories.ProductQuery) ([]*entities.Product, error) {
+	products, err := r.GetAll(query)
+
+	if err != nil || offset >= len(products) {
+		return []*entities.Product{}, err
+	}
+
+	return products[offset:min(offset+limit, len(products))], nil
+}
+
+func (r *productRepository) GetAll(query repositories.ProductQuery) ([]*entities.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]*entities.Product, 0, len(r.ids))
+
+	for _, id := range r.ids {
+		if product := r.products[id]; visible(product, query) {
+			products = append(products, product)
+		}
+	}
+
+	return products, nil
+}
+
+func (r *productRepository) Create(product *entities.Product) error {
+	product.ID = primitive.NewObjectID()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.put(product)
+
+	return nil
+}
+
+func (r *productRepository) Update(product *entities.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[product.ID.Hex()]; !ok {
+		return fmt.Errorf("product %s not found", product.ID.Hex())
+	}
+
+	r.products[product.ID.Hex()] = product
+
+	return nil
+}
+
+func (r *productRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[id]; !ok {
+		return nil
+	}
+
+	delete(r.products, id)
+
+	for i, existing := range r.ids {
+		if existing == id {
+			r.ids = append(r.ids[:i], r.ids[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// IncrementCounters adds to the click and sold counts of a product
+func (r *productRepository) IncrementCounters(id string, clicks, sold int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[id]
+
+	if !ok {
+		return nil
+	}
+
+	updated := *product
+	updated.ClickCount += clicks
+	updated.SoldCount += sold
+	r.products[id] = &updated
+
+	return nil
+}
+
+// put stores a product, keeping its position when it already exists. The lock must be held.
+func (r *productRepository) put(product *entities.Product) {
+	id := product.ID.Hex()
+
+	if _, ok := r.products[id]; !ok {
+		r.ids = append(r.ids, id)
+	}
+
+	r.products[id] = product
+}
+
+// visible tells whether the query lists the product
+func visible(product *entities.Product, query repositories.ProductQuery) bool {
+	return product.Published || query.IncludeUnpublished
+}
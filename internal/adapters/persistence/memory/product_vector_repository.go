@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"sync"
+)
+
+type productVectorRepository struct {
+	mu      sync.RWMutex
+	vectors map[string]*entities.ProductVector
+}
+
+func NewProductVectorRepository() repositories.ProductVectorRepository {
+	return &productVectorRepository{vectors: make(map[string]*entities.ProductVector)}
+}
+
+func (r *productVectorRepository) GetAll() ([]*entities.ProductVector, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vectors := make([]*entities.ProductVector, 0, len(r.vectors))
+
+	for _, vector := range r.vectors {
+		vectors = append(vectors, vector)
+	}
+
+	return vectors, nil
+}
+
+func (r *productVectorRepository) Upsert(vector *entities.ProductVector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.vectors[vector.ProductID.Hex()] = vector
+
+	return nil
+}
+
+func (r *productVectorRepository) Delete(productID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.vectors, productID)
+
+	return nil
+}
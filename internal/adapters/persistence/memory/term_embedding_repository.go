@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"sync"
+)
+
+type termEmbeddingRepository struct {
+	mu    sync.RWMutex
+	terms []*entities.TermEmbedding
+}
+
+func NewTermEmbeddingRepository() repositories.TermEmbeddingRepository {
+	return &termEmbeddingRepository{}
+}
+
+func (r *termEmbeddingRepository) GetAll() ([]*entities.TermEmbedding, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*entities.TermEmbedding(nil), r.terms...), nil
+}
+
+func (r *termEmbeddingRepository) ReplaceAll(terms []*entities.TermEmbedding) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.terms = append([]*entities.TermEmbedding(nil), terms...)
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type eventRepository struct {
+	collection *mongo.Collection
+}
+
+// duplicateKeyCode is the MongoDB error code of a write breaking a unique index
+const duplicateKeyCode = 11000
+
+// NewEventRepository returns the event repository, making sure client event IDs are unique
+func NewEventRepository(db *mongo.Client, dbName, collectionName string) repositories.EventRepository {
+	collection := db.Database(dbName).Collection(collectionName)
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "eventId", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"eventId": bson.M{"$exists": true}}),
+	})
+
+	if err != nil {
+		log.Printf("Error creating the event ID index, retried events may be counted twice: %v", err)
+	}
+
+	return &eventRepository{collection: collection}
+}
+
+// InsertMany stores the events whose client event ID isn't stored yet, assigning their IDs, and returns them
+func (r *eventRepository) InsertMany(events []*entities.Event) ([]*entities.Event, error) {
+	documents := make([]interface{}, 0, len(events))
+
+	for _, event := range events {
+		event.ID = primitive.NewObjectID()
+		documents = append(documents, event)
+	}
+
+	// Unordered, so the events following an already stored one are still inserted
+	_, err := r.collection.InsertMany(context.TODO(), documents, options.InsertMany().SetOrdered(false))
+
+	if err == nil {
+		return events, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return nil, err
+	}
+
+	duplicates := make(map[int]bool, len(bulkErr.WriteErrors))
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return nil, err
+		}
+
+		duplicates[writeErr.Index] = true
+	}
+
+	inserted := make([]*entities.Event, 0, len(events)-len(duplicates))
+
+	for i, event := range events {
+		if !duplicates[i] {
+			inserted = append(inserted, event)
+		}
+	}
+
+	return inserted, nil
+}
+
+// GetByTypes returns every event of the given types, oldest first
+func (r *eventRepository) GetByTypes(types []entities.EventType) ([]*entities.Event, error) {
+	return r.find(bson.M{"type": bson.M{"$in": types}})
+}
+
+// GetSince returns the events that happened from the given time on, oldest first
+func (r *eventRepository) GetSince(since time.Time) ([]*entities.Event, error) {
+	return r.find(bson.M{"timestamp": bson.M{"$gte": since}})
+}
+
+// GetReceivedSince returns the events ingested from the given time on, whenever they happened
+func (r *eventRepository) GetReceivedSince(since time.Time) ([]*entities.Event, error) {
+	return r.find(bson.M{"receivedAt": bson.M{"$gte": since}})
+}
+
+// GetBySession returns the latest events of a session, newest first
+func (r *eventRepository) GetBySession(sessionID string, limit int) ([]*entities.Event, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(int64(limit))
+
+	return r.find(bson.M{"sessionId": sessionID}, opts)
+}
+
+// GetByExperiment returns the events of the given types stamped with an experiment, oldest first
+func (r *eventRepository) GetByExperiment(experiment string, types []entities.EventType) ([]*entities.Event, error) {
+	return r.find(bson.M{"experiment": experiment, "type": bson.M{"$in": types}})
+}
+
+func (r *eventRepository) find(filter bson.M, opts ...*options.FindOptions) ([]*entities.Event, error) {
+	var events []*entities.Event
+
+	if len(opts) == 0 {
+		opts = append(opts, options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}}))
+	}
+
+	cursor, err := r.collection.Find(context.TODO(), filter, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var event entities.Event
+
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+
+		events = append(events, &event)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
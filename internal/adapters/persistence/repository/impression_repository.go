@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type impressionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewImpressionRepository(db *mongo.Client, dbName, collectionName string) repositories.ImpressionRepository {
+	return &impressionRepository{
+		collection: db.Database(dbName).Collection(collectionName),
+	}
+}
+
+// Insert stores an impression, assigning its ID
+func (r *impressionRepository) Insert(impression *entities.Impression) error {
+	impression.ID = primitive.NewObjectID()
+
+	_, err := r.collection.InsertOne(context.TODO(), impression)
+
+	return err
+}
+
+// GetByExperiment returns every impression served in an experiment
+func (r *impressionRepository) GetByExperiment(experiment string) ([]*entities.Impression, error) {
+	var impressions []*entities.Impression
+
+	cursor, err := r.collection.Find(context.TODO(), bson.M{"experiment": experiment})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var impression entities.Impression
+
+		if err := cursor.Decode(&impression); err != nil {
+			return nil, err
+		}
+
+		impressions = append(impressions, &impression)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return impressions, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type itemSimilarityRepository struct {
+	collection *mongo.Collection
+}
+
+func NewItemSimilarityRepository(db *mongo.Client, dbName, collectionName string) repositories.ItemSimilarityRepository {
+	return &itemSimilarityRepository{
+		collection: db.Database(dbName).Collection(collectionName),
+	}
+}
+
+// GetByProductID returns the similarities of a product, or nil when the batch job found none
+func (r *itemSimilarityRepository) GetByProductID(productID string) (*entities.ItemSimilarity, error) {
+	var similarity entities.ItemSimilarity
+
+	err := r.collection.FindOne(context.TODO(), bson.M{"_id": productID}).Decode(&similarity)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &similarity, nil
+}
+
+// ReplaceAll swaps the stored similarities for a freshly computed set.
+// Documents are replaced one by one, so readers never see an empty collection.
+func (r *itemSimilarityRepository) ReplaceAll(similarities []*entities.ItemSimilarity) error {
+	ids := make([]string, 0, len(similarities))
+	models := make([]mongo.WriteModel, 0, len(similarities))
+
+	for _, similarity := range similarities {
+		ids = append(ids, similarity.ProductID)
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": similarity.ProductID}).
+			SetReplacement(similarity).
+			SetUpsert(true))
+	}
+
+	if len(models) > 0 {
+		if _, err := r.collection.BulkWrite(context.TODO(), models); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.collection.DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$nin": ids}})
+	return err
+}
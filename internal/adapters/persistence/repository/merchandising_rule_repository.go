@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type merchandisingRuleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMerchandisingRuleRepository(db *mongo.Client, dbName, collectionName string) repositories.MerchandisingRuleRepository {
+	return &merchandisingRuleRepository{
+		collection: db.Database(dbName).Collection(collectionName),
+	}
+}
+
+func (r *merchandisingRuleRepository) GetAll() ([]*entities.MerchandisingRule, error) {
+	rules := []*entities.MerchandisingRule{}
+
+	cursor, err := r.collection.Find(context.TODO(), bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var rule entities.MerchandisingRule
+
+		if err := cursor.Decode(&rule); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, &rule)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *merchandisingRuleRepository) GetByID(id string) (*entities.MerchandisingRule, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rule entities.MerchandisingRule
+
+	if err := r.collection.FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&rule); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// Create stores a rule, assigning its ID
+func (r *merchandisingRuleRepository) Create(rule *entities.MerchandisingRule) error {
+	rule.ID = primitive.NewObjectID()
+
+	_, err := r.collection.InsertOne(context.TODO(), rule)
+
+	return err
+}
+
+// Update replaces a rule as a whole, so clearing a field clears it in the stored rule
+func (r *merchandisingRuleRepository) Update(rule *entities.MerchandisingRule) error {
+	result, err := r.collection.ReplaceOne(context.TODO(), bson.M{"_id": rule.ID}, rule)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (r *merchandisingRuleRepository) Delete(id string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(context.TODO(), bson.M{"_id": objectId})
+
+	return err
+}
@@ -44,14 +44,61 @@ func (r *productRepository) GetByID(id string) (*entities.Product, error) {
     return &product, nil
 }
 
-func (r *productRepository) GetPaginated(offset, limit int) ([]*entities.Product, error) {
+// GetByIDs fetches the given products, returned in the same order as ids. Unknown ids, and unpublished ones
+// unless the query includes them, are skipped.
+func (r *productRepository) GetByIDs(ids []string, query repositories.ProductQuery) ([]*entities.Product, error) {
+    objectIds := make([]primitive.ObjectID, 0, len(ids))
+
+    for _, id := range ids {
+        objectId, err := primitive.ObjectIDFromHex(id)
+
+        if err != nil {
+            return nil, err
+        }
+
+        objectIds = append(objectIds, objectId)
+    }
+
+    cursor, err := r.collection.Find(context.TODO(), visibilityFilter(bson.M{"_id": bson.M{"$in": objectIds}}, query))
+
+    if err != nil {
+        return nil, err
+    }
+    defer cursor.Close(context.TODO())
+
+    byID := make(map[string]*entities.Product, len(ids))
+
+    for cursor.Next(context.TODO()) {
+        var product entities.Product
+        if err := cursor.Decode(&product); err != nil {
+            return nil, err
+        }
+        byID[product.ID.Hex()] = &product
+    }
+
+    if err := cursor.Err(); err != nil {
+        return nil, err
+    }
+
+    products := make([]*entities.Product, 0, len(byID))
+
+    for _, id := range ids {
+        if product, ok := byID[id]; ok {
+            products = append(products, product)
+        }
+    }
+
+    return products, nil
+}
+
+func (r *productRepository) GetPaginated(offset, limit int, query repositories.ProductQuery) ([]*entities.Product, error) {
     var products []*entities.Product
 
     findOptions := options.Find()
     findOptions.SetSkip(int64(offset))
     findOptions.SetLimit(int64(limit))
     
-    cursor, err := r.collection.Find(context.TODO(), bson.M{}, findOptions)
+    cursor, err := r.collection.Find(context.TODO(), visibilityFilter(bson.M{}, query), findOptions)
 
     if err != nil {
         return nil, err
@@ -74,10 +121,10 @@ func (r *productRepository) GetPaginated(offset, limit int) ([]*entities.Product
     return products, nil
 }
 
-func (r *productRepository) GetAll() ([]*entities.Product, error) {
+func (r *productRepository) GetAll(query repositories.ProductQuery) ([]*entities.Product, error) {
     var products []*entities.Product
     
-    cursor, err := r.collection.Find(context.TODO(), bson.M{})
+    cursor, err := r.collection.Find(context.TODO(), visibilityFilter(bson.M{}, query))
 
     if err != nil {
         return nil, err
@@ -121,7 +168,8 @@ func (r *productRepository) Update(product *entities.Product) error {
         tagParts := strings.Split(jsonTag, ",")
         tag := tagParts[0]
 
-        if !isZeroType(val) {
+        // Unpublishing writes false, so the flag is always set
+        if !isZeroType(val) || tag == "published" {
             update := bson.E{Key: tag, Value: val.Interface()}
             
             updates = append(updates, update)
@@ -154,3 +202,23 @@ func (r *productRepository) Delete(id string) error {
     return err
 }
 
+// IncrementCounters atomically adds to the click and sold counts of a product
+func (r *productRepository) IncrementCounters(id string, clicks, sold int) error {
+    objectId, err := primitive.ObjectIDFromHex(id)
+
+    if err != nil {
+        return err
+    }
+
+    _, err = r.collection.UpdateOne(context.TODO(), bson.M{"_id": objectId}, bson.M{"$inc": bson.M{"clickCount": clicks, "soldCount": sold}})
+    return err
+}
+
+// visibilityFilter restricts a filter to the published products, unless the query includes the others
+func visibilityFilter(filter bson.M, query repositories.ProductQuery) bson.M {
+    if !query.IncludeUnpublished {
+        filter["published"] = true
+    }
+
+    return filter
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type productVectorRepository struct {
+	collection *mongo.Collection
+}
+
+func NewProductVectorRepository(db *mongo.Client, dbName, collectionName string) repositories.ProductVectorRepository {
+	return &productVectorRepository{
+		collection: db.Database(dbName).Collection(collectionName),
+	}
+}
+
+func (r *productVectorRepository) GetAll() ([]*entities.ProductVector, error) {
+	var vectors []*entities.ProductVector
+
+	cursor, err := r.collection.Find(context.TODO(), bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var vector entities.ProductVector
+
+		if err := cursor.Decode(&vector); err != nil {
+			return nil, err
+		}
+
+		vectors = append(vectors, &vector)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return vectors, nil
+}
+
+func (r *productVectorRepository) Upsert(vector *entities.ProductVector) error {
+	filter := bson.M{"_id": vector.ProductID}
+
+	_, err := r.collection.ReplaceOne(context.TODO(), filter, vector, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *productVectorRepository) Delete(productID string) error {
+	objectId, err := primitive.ObjectIDFromHex(productID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(context.TODO(), bson.M{"_id": objectId})
+	return err
+}
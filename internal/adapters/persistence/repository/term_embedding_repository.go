@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type termEmbeddingRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTermEmbeddingRepository(db *mongo.Client, dbName, collectionName string) repositories.TermEmbeddingRepository {
+	return &termEmbeddingRepository{
+		collection: db.Database(dbName).Collection(collectionName),
+	}
+}
+
+func (r *termEmbeddingRepository) GetAll() ([]*entities.TermEmbedding, error) {
+	var terms []*entities.TermEmbedding
+
+	cursor, err := r.collection.Find(context.TODO(), bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var term entities.TermEmbedding
+
+		if err := cursor.Decode(&term); err != nil {
+			return nil, err
+		}
+
+		terms = append(terms, &term)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return terms, nil
+}
+
+// ReplaceAll swaps the stored model for a freshly trained one.
+// Terms are replaced one by one, so readers never see an empty collection.
+func (r *termEmbeddingRepository) ReplaceAll(terms []*entities.TermEmbedding) error {
+	ids := make([]string, 0, len(terms))
+	models := make([]mongo.WriteModel, 0, len(terms))
+
+	for _, term := range terms {
+		ids = append(ids, term.Term)
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": term.Term}).
+			SetReplacement(term).
+			SetUpsert(true))
+	}
+
+	if len(models) > 0 {
+		if _, err := r.collection.BulkWrite(context.TODO(), models); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.collection.DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$nin": ids}})
+	return err
+}
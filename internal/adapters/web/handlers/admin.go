@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader carries the token of back-office requests
+const AdminTokenHeader = "X-Admin-Token"
+
+// adminScopeKey marks the requests made with the admin token in the gin context
+const adminScopeKey = "adminScope"
+
+// errAdminScopeRequired is returned when a request asks for something only back-office tools may see
+var errAdminScopeRequired = errors.New("includeUnpublished requires the admin token")
+
+// AdminScope marks the requests bearing the admin token as admin scoped.
+// Without a token configured no request is.
+func AdminScope(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(AdminTokenHeader)
+
+		if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			c.Set(adminScopeKey, true)
+		}
+
+		c.Next()
+	}
+}
+
+// parseIncludeUnpublished reads the includeUnpublished query parameter, which only admin scoped requests may set
+func parseIncludeUnpublished(c *gin.Context) (bool, error) {
+	includeStr, ok := c.GetQuery("includeUnpublished")
+
+	if !ok {
+		return false, nil
+	}
+
+	include, err := strconv.ParseBool(includeStr)
+
+	if err != nil {
+		return false, fmt.Errorf("includeUnpublished must be true or false")
+	}
+
+	if include && !c.GetBool(adminScopeKey) {
+		return false, errAdminScopeRequired
+	}
+
+	return include, nil
+}
+
+// handleOptionsError rejects a request whose query parameters couldn't be parsed
+func handleOptionsError(c *gin.Context, err error) {
+	if errors.Is(err, errAdminScopeRequired) {
+		HandleError(c, http.StatusForbidden, err)
+		return
+	}
+
+	HandleError(c, http.StatusBadRequest, err)
+}
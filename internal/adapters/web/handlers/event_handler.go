@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EventHandler struct {
+	eventService services.EventService
+}
+
+func NewEventHandler(eventService services.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+	}
+}
+
+// RecordEvents ingests a batch of interaction events
+func (h *EventHandler) RecordEvents(c *gin.Context) {
+	var batch entities.EventBatch
+
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	validationErrors := entities.ValidateStruct(&batch)
+
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validationErrors": validationErrors})
+		return
+	}
+
+	for i, event := range batch.Events {
+		if _, err := primitive.ObjectIDFromHex(event.ProductID); err != nil {
+			HandleError(c, http.StatusBadRequest, fmt.Errorf("events[%d].productId is not a valid product id", i))
+			return
+		}
+	}
+
+	recorded, err := h.eventService.RecordEvents(batch.Events)
+
+	var notFound *services.ProductsNotFoundError
+
+	if errors.As(err, &notFound) {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Events already recorded, e.g. by a retry, are not recorded again
+	c.JSON(http.StatusCreated, gin.H{"recorded": recorded, "duplicates": len(batch.Events) - recorded})
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"backend-challenge/internal/application/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExperimentHandler struct {
+	experimentService *services.ExperimentService
+}
+
+func NewExperimentHandler(experimentService *services.ExperimentService) *ExperimentHandler {
+	return &ExperimentHandler{
+		experimentService: experimentService,
+	}
+}
+
+// GetReport reports the click-through of each arm of an experiment, the running one unless a name is given
+func (h *ExperimentHandler) GetReport(c *gin.Context) {
+	report, err := h.experimentService.Report(c.Query("name"))
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MerchandisingRuleHandler struct {
+	merchandisingService services.MerchandisingService
+}
+
+func NewMerchandisingRuleHandler(merchandisingService services.MerchandisingService) *MerchandisingRuleHandler {
+	return &MerchandisingRuleHandler{
+		merchandisingService: merchandisingService,
+	}
+}
+
+// GetAllRules lists the merchandising rules in the order they are applied
+func (h *MerchandisingRuleHandler) GetAllRules(c *gin.Context) {
+	rules, err := h.merchandisingService.GetAllRules()
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *MerchandisingRuleHandler) GetRuleByID(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	rule, err := h.merchandisingService.GetRule(id)
+
+	if err != nil {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *MerchandisingRuleHandler) CreateRule(c *gin.Context) {
+	var rule entities.MerchandisingRule
+
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	validationErrors := entities.ValidateStruct(&rule)
+
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validationErrors": validationErrors})
+		return
+	}
+
+	now := time.Now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if err := h.merchandisingService.CreateRule(&rule); err != nil {
+		handleMerchandisingRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule replaces a rule as a whole
+func (h *MerchandisingRuleHandler) UpdateRule(c *gin.Context) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var rule entities.MerchandisingRule
+
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	validationErrors := entities.ValidateStruct(&rule)
+
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validationErrors": validationErrors})
+		return
+	}
+
+	existing, err := h.merchandisingService.GetRule(id)
+
+	if err != nil {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+
+	rule.ID = objectId
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	if err := h.merchandisingService.UpdateRule(&rule); err != nil {
+		handleMerchandisingRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *MerchandisingRuleHandler) DeleteRule(c *gin.Context) {
+	if err := h.merchandisingService.DeleteRule(c.Param("id")); err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleMerchandisingRuleError tells rules that make no sense apart from failures to store them
+func handleMerchandisingRuleError(c *gin.Context, err error) {
+	var invalid *services.InvalidMerchandisingRuleError
+
+	if errors.As(err, &invalid) {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	HandleError(c, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"backend-challenge/internal/application/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsHandler struct {
+	recommendationCache *services.RecommendationCache
+}
+
+func NewMetricsHandler(recommendationCache *services.RecommendationCache) *MetricsHandler {
+	return &MetricsHandler{
+		recommendationCache: recommendationCache,
+	}
+}
+
+// GetMetrics reports the counters used to monitor the service, like the recommendation cache hits and misses
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"recommendationCache": h.recommendationCache.Stats(),
+	})
+}
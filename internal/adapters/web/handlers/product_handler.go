@@ -3,9 +3,14 @@ package handlers
 import (
 	"backend-challenge/internal/application/services"
 	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,21 +19,24 @@ import (
 )
 
 type ProductHandler struct {
-	productService services.ProductService
-	// brainService	services.BrainService
+	productService    services.ProductService
+	brainService      *services.BrainService
+	experimentService *services.ExperimentService
 }
 
 func generateNewID() string {
 	return uuid.New().String()
 }
 
-func NewProductHandler(productService services.ProductService, /*brainService services.BrainService */) *ProductHandler {
+func NewProductHandler(productService services.ProductService, brainService *services.BrainService, experimentService *services.ExperimentService) *ProductHandler {
 	return &ProductHandler{
-		productService: productService,
-		// brainService: *services.NewBrainService(15),
+		productService:    productService,
+		brainService:      brainService,
+		experimentService: experimentService,
 	}
 }
 
+// GetProductByID fetches a published product, or an unpublished one for admin scoped requests asking for it.
 func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	id := c.Param("id")
 
@@ -39,7 +47,14 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.GetProductByID(productId.Hex())
+	includeUnpublished, err := parseIncludeUnpublished(c)
+
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
+
+	product, err := h.productService.GetProductByID(productId.Hex(), repositories.ProductQuery{IncludeUnpublished: includeUnpublished})
 
 	if err != nil {
 		HandleError(c, http.StatusNotFound, err)
@@ -49,15 +64,18 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	c.JSON(http.StatusOK, product)
 }
 
+// GetAllProducts lists the published products, and the unpublished ones too for admin scoped requests asking for them,
+// along with the products among them suggested by the boundaries and rules of the request.
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 
-	// _, limit, offset := getPaginationParams(c)
-
-	// paginatedProducts, err := h.productService.GetPaginatedProducts(offset, limit)
+	includeUnpublished, err := parseIncludeUnpublished(c)
 
-	allProducts, err := h.productService.GetAllProducts()
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
 
-	log.Printf("Error retrieving products: %v", err)
+	products, err := h.productService.GetAllProducts(repositories.ProductQuery{IncludeUnpublished: includeUnpublished})
 
 	if err != nil {
 		log.Printf("Error retrieving products: %v", err)
@@ -65,15 +83,19 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 		return
 	}
 
+	boundaries, rules, err := parseRecommendationParams(c)
 
-	// boundaries, rules := parseRecommendationParams(c)
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
 
-	// recommendations, metadata := h.brainService.GenerateProductSuggestions(allProducts, paginatedProducts, boundaries, rules)
+	recommendations, metadata := h.brainService.GenerateProductSuggestions(products, nil, boundaries, rules)
 
 	c.JSON(http.StatusOK, gin.H{
-		"products": allProducts,
-		// "recommendations": recommendations,
-		// "recommendaiton_metadata": metadata,
+		"products":               products,
+		"recommendations":        recommendations,
+		"recommendationMetadata": metadata,
 	})
 }
 
@@ -95,8 +117,13 @@ func getPaginationParams(c *gin.Context) (page, limit, offset int) {
 	return
 }
 
-func parseRecommendationParams(c *gin.Context) ([]entities.BrainBoundary, []entities.BrainRule) {
-	priceLimit := parsePriceLimit(c)
+func parseRecommendationParams(c *gin.Context) ([]entities.BrainBoundary, []entities.BrainRule, error) {
+	priceLimit, err := parsePriceLimit(c)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
 	onlyInStock := c.DefaultQuery("onlyInStock", "false") == "true"
 	categories := c.QueryArray("categories")
 	prioritizeCategories := c.QueryArray("prioritizeCategories")
@@ -120,20 +147,110 @@ func parseRecommendationParams(c *gin.Context) ([]entities.BrainBoundary, []enti
 		},
 	}
 
-	return boundaries, rules
+	return boundaries, rules, nil
 }
 
-func parsePriceLimit(c *gin.Context) *float64 {
+// parseRecommendationOptions reads the strategy, limit, offset, minScore, explain, diversify, lambda,
+// maxPerCategory, outOfStockPenalty and includeUnpublished query parameters.
+// Unlike the listing pagination, invalid values are rejected instead of silently defaulted.
+func parseRecommendationOptions(c *gin.Context) (services.RecommendationOptions, error) {
+	opts := services.DefaultRecommendationOptions()
+
+	if strategy, ok := c.GetQuery("strategy"); ok {
+		if !slices.Contains(services.Strategies, strategy) {
+			return opts, fmt.Errorf("strategy must be one of %s", strings.Join(services.Strategies, ", "))
+		}
+		opts.Strategy = strategy
+	}
+
+	if limitStr, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > services.MaxRecommendationLimit {
+			return opts, fmt.Errorf("limit must be an integer between 1 and %d", services.MaxRecommendationLimit)
+		}
+		opts.Limit = limit
+	}
+
+	if offsetStr, ok := c.GetQuery("offset"); ok {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("offset must be a non-negative integer")
+		}
+		opts.Offset = offset
+	}
+
+	if minScoreStr, ok := c.GetQuery("minScore"); ok {
+		minScore, err := strconv.ParseFloat(minScoreStr, 64)
+		if err != nil || minScore < 0 || minScore > 1 {
+			return opts, fmt.Errorf("minScore must be a number between 0 and 1")
+		}
+		opts.MinScore = minScore
+	}
+
+	if explainStr, ok := c.GetQuery("explain"); ok {
+		explain, err := strconv.ParseBool(explainStr)
+		if err != nil {
+			return opts, fmt.Errorf("explain must be true or false")
+		}
+		opts.Explain = explain
+	}
+
+	if diversifyStr, ok := c.GetQuery("diversify"); ok {
+		diversify, err := strconv.ParseBool(diversifyStr)
+		if err != nil {
+			return opts, fmt.Errorf("diversify must be true or false")
+		}
+		opts.Diversify = diversify
+	}
+
+	// A lambda on its own also asks for diversity
+	if lambdaStr, ok := c.GetQuery("lambda"); ok {
+		lambda, err := strconv.ParseFloat(lambdaStr, 64)
+		if err != nil || lambda < 0 || lambda > 1 {
+			return opts, fmt.Errorf("lambda must be a number between 0 and 1")
+		}
+		opts.Diversify = true
+		opts.Lambda = lambda
+	}
+
+	if maxPerCategoryStr, ok := c.GetQuery("maxPerCategory"); ok {
+		maxPerCategory, err := strconv.Atoi(maxPerCategoryStr)
+		if err != nil || maxPerCategory < 1 {
+			return opts, fmt.Errorf("maxPerCategory must be a positive integer")
+		}
+		opts.MaxPerCategory = maxPerCategory
+	}
+
+	if penaltyStr, ok := c.GetQuery("outOfStockPenalty"); ok {
+		penalty, err := strconv.ParseFloat(penaltyStr, 64)
+		if err != nil || penalty < 0 || penalty > 1 {
+			return opts, fmt.Errorf("outOfStockPenalty must be a number between 0 and 1")
+		}
+		opts.OutOfStockPenalty = penalty
+	}
+
+	includeUnpublished, err := parseIncludeUnpublished(c)
+
+	if err != nil {
+		return opts, err
+	}
+
+	opts.IncludeUnpublished = includeUnpublished
+
+	return opts, nil
+}
+
+func parsePriceLimit(c *gin.Context) (*float64, error) {
 	priceLimitStr := c.Query("priceLimit")
 	if priceLimitStr == "" {
-		return nil
+		return nil, nil
 	}
 	limit, err := strconv.ParseFloat(priceLimitStr, 64)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("priceLimit must be a number")
 	}
 
-	return &limit
+	return &limit, nil
 }
 
 func (h *ProductHandler) CreateProduct(c *gin.Context) {
@@ -162,6 +279,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	c.JSON(http.StatusCreated, product)
 }
 
+// UpdateProduct writes the fields set in the body. Leaving published out keeps the stored flag.
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	id := c.Param("id")
 
@@ -171,6 +289,14 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	var published struct {
+		Published *bool `json:"published"`
+	}
+	if err := c.ShouldBindBodyWithJSON(&published); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
 	validationErrors := entities.ValidateStruct(&product)
 
 	if validationErrors != nil {
@@ -187,6 +313,17 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	
 	product.ID = objectId
 
+	if published.Published == nil {
+		stored, err := h.productService.GetProductByID(objectId.Hex(), repositories.ProductQuery{IncludeUnpublished: true})
+
+		if err != nil {
+			HandleError(c, http.StatusNotFound, err)
+			return
+		}
+
+		product.Published = stored.Published
+	}
+
 	if err := h.productService.UpdateProduct(&product); err != nil {
 		
 		log.Printf("ERROR: %v", err)
@@ -213,12 +350,77 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 func (h *ProductHandler) GetRecommendations(c *gin.Context) {
 	productID := c.Param("id")
 
-	recommendations, err := h.productService.GetRecommendations(productID)
+	if _, err := primitive.ObjectIDFromHex(productID); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	opts, err := parseRecommendationOptions(c)
+
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
+
+	opts.Boundaries, opts.Rules, err = parseRecommendationParams(c)
+
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
+
+	// Sessions and users are bucketed into the running experiment, unless the request picks its own strategy
+	unit := services.ExperimentUnit(c.Query("sessionId"), c.Query("userId"))
+
+	var assignment *services.ExperimentAssignment
+
+	if _, ok := c.GetQuery("strategy"); !ok {
+		opts, assignment = h.experimentService.Apply(unit, opts)
+	}
+
+	result, err := h.productService.GetRecommendations(productID, opts)
+
+	var unknownStrategy *services.UnknownStrategyError
+	var notFound *services.ProductsNotFoundError
+
+	if errors.As(err, &unknownStrategy) {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if errors.As(err, &notFound) {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	result.Experiment = assignment
+	h.experimentService.RecordImpression(unit, assignment, productID, result)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAlsoBought lists the products bought or viewed together with a product
+func (h *ProductHandler) GetAlsoBought(c *gin.Context) {
+	productID := c.Param("id")
+
+	opts, err := parseRecommendationOptions(c)
+
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
+
+	result, err := h.productService.GetAlsoBought(productID, opts)
 
 	if err != nil {
 		HandleError(c, http.StatusInternalServerError, err)
 		return
 	}
 
- 	c.JSON(http.StatusOK, recommendations)
+	c.JSON(http.StatusOK, result)
 }
\ No newline at end of file
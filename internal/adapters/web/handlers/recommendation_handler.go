@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RecommendationHandler struct {
+	sessionRecommendationService services.SessionRecommendationService
+	batchRecommendationService   services.BatchRecommendationService
+}
+
+func NewRecommendationHandler(sessionRecommendationService services.SessionRecommendationService, batchRecommendationService services.BatchRecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{
+		sessionRecommendationService: sessionRecommendationService,
+		batchRecommendationService:   batchRecommendationService,
+	}
+}
+
+// GetSessionRecommendations recommends products for a session from what it recently viewed and added to the cart
+func (h *RecommendationHandler) GetSessionRecommendations(c *gin.Context) {
+	sessionID := c.Query("sessionId")
+
+	if sessionID == "" {
+		HandleError(c, http.StatusBadRequest, fmt.Errorf("sessionId is required"))
+		return
+	}
+
+	opts, err := parseRecommendationOptions(c)
+
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
+
+	result, err := h.sessionRecommendationService.GetSessionRecommendations(sessionID, opts)
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetBatchRecommendations recommends products for several products at once, either per product or merged
+func (h *RecommendationHandler) GetBatchRecommendations(c *gin.Context) {
+	var batch entities.RecommendationBatch
+
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	validationErrors := entities.ValidateStruct(&batch)
+
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validationErrors": validationErrors})
+		return
+	}
+
+	for i, id := range batch.ProductIDs {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			HandleError(c, http.StatusBadRequest, fmt.Errorf("productIds[%d] is not a valid product id", i))
+			return
+		}
+	}
+
+	opts, err := parseRecommendationOptions(c)
+
+	if err != nil {
+		handleOptionsError(c, err)
+		return
+	}
+
+	// Scoring every seed in one pass is only possible by content similarity
+	if opts.Strategy != "" && opts.Strategy != services.StrategyContent {
+		HandleError(c, http.StatusBadRequest, fmt.Errorf("batch recommendations only support the %s strategy", services.StrategyContent))
+		return
+	}
+
+	result, err := h.batchRecommendationService.GetBatchRecommendations(batch.ProductIDs, batch.Merge, opts)
+
+	var notFound *services.ProductsNotFoundError
+
+	if errors.As(err, &notFound) {
+		HandleError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
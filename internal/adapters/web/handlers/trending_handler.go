@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"backend-challenge/internal/application/services"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrendingHandler struct {
+	trendingService services.TrendingService
+}
+
+func NewTrendingHandler(trendingService services.TrendingService) *TrendingHandler {
+	return &TrendingHandler{
+		trendingService: trendingService,
+	}
+}
+
+// GetTrending lists the products with the most recent interactions, optionally of one category or store
+func (h *TrendingHandler) GetTrending(c *gin.Context) {
+	opts, err := parseTrendingOptions(c)
+
+	if err != nil {
+		HandleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.trendingService.GetTrending(opts)
+
+	if err != nil {
+		HandleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseTrendingOptions reads the category, storeId, limit and offset query parameters
+func parseTrendingOptions(c *gin.Context) (services.TrendingOptions, error) {
+	opts := services.TrendingOptions{
+		Category: c.Query("category"),
+		StoreID:  c.Query("storeId"),
+		Limit:    services.DefaultTrendingLimit,
+	}
+
+	if limitStr, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > services.MaxTrendingLimit {
+			return opts, fmt.Errorf("limit must be an integer between 1 and %d", services.MaxTrendingLimit)
+		}
+		opts.Limit = limit
+	}
+
+	if offsetStr, ok := c.GetQuery("offset"); ok {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("offset must be a non-negative integer")
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/kljensen/snowball"
+)
+
+// Analyzer turns text written in one language into the tokens used as features
+type Analyzer interface {
+	Analyze(text string) []string
+}
+
+// AnalyzerConfig selects and tunes the analyzer of a language
+type AnalyzerConfig struct {
+	Stemmer        string              `json:"stemmer"`        // snowball language ("english", "spanish", ...) or "none"
+	ExtraStopwords []string            `json:"extraStopwords"` // added to the built-in list of the language
+	Synonyms       map[string][]string `json:"synonyms"`       // word -> words also emitted when it appears
+}
+
+// defaultAnalyzerConfigs are used for languages the configuration doesn't mention.
+// The snowball package has no Portuguese stemmer, so Portuguese only removes stopwords.
+var defaultAnalyzerConfigs = map[string]AnalyzerConfig{
+	"en": {Stemmer: "english"},
+	"es": {Stemmer: "spanish"},
+	"pt": {Stemmer: "none"},
+}
+
+// languageAnalyzer normalizes text, drops stopwords, expands synonyms and stems what's left
+type languageAnalyzer struct {
+	stopwords map[string]bool
+	stemmer   string
+	synonyms  map[string][]string
+}
+
+// NewLanguageAnalyzer builds an analyzer from stopwords, a snowball stemmer name and synonyms.
+// Words are accent folded the same way analyzed text is, so they can be given in their natural form.
+func NewLanguageAnalyzer(stopwords []string, stemmer string, synonyms map[string][]string) Analyzer {
+	analyzer := &languageAnalyzer{
+		stopwords: make(map[string]bool, len(stopwords)),
+		stemmer:   stemmer,
+		synonyms:  make(map[string][]string, len(synonyms)),
+	}
+
+	// Contractions such as "don't" are split the same way analyzed text is
+	for _, stopword := range stopwords {
+		for _, word := range splitWords(NormalizeText(stopword)) {
+			analyzer.stopwords[word] = true
+		}
+	}
+
+	for word, expansions := range synonyms {
+		key := NormalizeText(word)
+
+		for _, expansion := range expansions {
+			analyzer.synonyms[key] = append(analyzer.synonyms[key], splitWords(NormalizeText(expansion))...)
+		}
+	}
+
+	return analyzer
+}
+
+// Analyze processes text by normalizing it, removing stopwords, expanding synonyms and applying stemming
+func (a *languageAnalyzer) Analyze(text string) []string {
+	var tokens []string
+
+	for _, word := range splitWords(NormalizeText(text)) {
+		if a.stopwords[word] { // Skip stopwords
+			continue
+		}
+
+		tokens = append(tokens, a.stem(word))
+
+		for _, synonym := range a.synonyms[word] {
+			tokens = append(tokens, a.stem(synonym))
+		}
+	}
+
+	return tokens
+}
+
+func (a *languageAnalyzer) stem(word string) string {
+	if a.stemmer == "" || a.stemmer == "none" {
+		return word
+	}
+
+	stemmed, err := snowball.Stem(word, a.stemmer, true)
+
+	if err != nil {
+		return word // Fallback to original word
+	}
+
+	return stemmed
+}
+
+// splitWords splits text into words, removing punctuation
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) // Remove non-alphanumeric
+	})
+}
+
+// AnalyzerRegistry resolves the analyzer of a language.
+// Languages without an analyzer fall back to English, as tokenizing did before analyzers existed.
+type AnalyzerRegistry struct {
+	mu        sync.RWMutex
+	analyzers map[string]Analyzer
+}
+
+// NewAnalyzerRegistry builds the analyzers of the default languages, overridden or extended by configs
+func NewAnalyzerRegistry(configs map[string]AnalyzerConfig) *AnalyzerRegistry {
+	registry := &AnalyzerRegistry{
+		analyzers: make(map[string]Analyzer),
+	}
+
+	merged := make(map[string]AnalyzerConfig, len(defaultAnalyzerConfigs))
+
+	for lang, config := range defaultAnalyzerConfigs {
+		merged[lang] = config
+	}
+
+	for lang, config := range configs {
+		if config.Stemmer == "" {
+			config.Stemmer = defaultAnalyzerConfigs[lang].Stemmer
+		}
+
+		merged[lang] = config
+	}
+
+	for lang, config := range merged {
+		stopwords := append(strings.Fields(defaultStopwords[lang]), config.ExtraStopwords...)
+
+		registry.Register(lang, NewLanguageAnalyzer(stopwords, config.Stemmer, config.Synonyms))
+	}
+
+	return registry
+}
+
+// Register sets the analyzer of a language, replacing any previous one
+func (r *AnalyzerRegistry) Register(lang string, analyzer Analyzer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.analyzers[lang] = analyzer
+}
+
+// Get returns the analyzer of a language, or the English one when the language has none
+func (r *AnalyzerRegistry) Get(lang string) Analyzer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if analyzer, ok := r.analyzers[lang]; ok {
+		return analyzer
+	}
+
+	return r.analyzers["en"]
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BatchRecommendationResult holds either the recommendations of each seed product, keyed by its ID,
+// or a single merged list
+type BatchRecommendationResult struct {
+	Results map[string]*RecommendationResult `json:"results,omitempty"`
+	Merged  *RecommendationResult            `json:"merged,omitempty"`
+}
+
+// ProductsNotFoundError is returned when some of the requested products don't exist
+type ProductsNotFoundError struct {
+	IDs []string
+}
+
+func (e *ProductsNotFoundError) Error() string {
+	return fmt.Sprintf("products not found: %s", strings.Join(e.IDs, ", "))
+}
+
+type BatchRecommendationService interface {
+	GetBatchRecommendations(productIDs []string, merge bool, opts RecommendationOptions) (*BatchRecommendationResult, error)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+)
+
+type batchRecommendationService struct {
+	repo    repositories.ProductRepository
+	vectors *VectorStore
+	scorer  *RecommendationService
+}
+
+func NewBatchRecommendationService(repo repositories.ProductRepository, vectors *VectorStore, scorer *RecommendationService) BatchRecommendationService {
+	return &batchRecommendationService{repo: repo, vectors: vectors, scorer: scorer}
+}
+
+// GetBatchRecommendations recommends products for several seed products at once, by content similarity.
+// The candidates of every seed are gathered and scored against the seeds that surfaced them in a single pass,
+// and every recommended product is loaded with one query.
+// Merged, a candidate scores its best similarity to any seed, and the seeds themselves are never recommended.
+func (s *batchRecommendationService) GetBatchRecommendations(productIDs []string, merge bool, opts RecommendationOptions) (*BatchRecommendationResult, error) {
+	seeds := make([]string, 0, len(productIDs))
+	isSeed := make(map[string]bool, len(productIDs))
+
+	for _, id := range productIDs {
+		if !isSeed[id] {
+			isSeed[id] = true
+			seeds = append(seeds, id)
+		}
+	}
+
+	// Seeds are looked up like single products, whether published or not
+	products, err := s.repo.GetByIDs(seeds, repositories.ProductQuery{IncludeUnpublished: true})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(products) < len(seeds) {
+		found := make(map[string]bool, len(products))
+
+		for _, product := range products {
+			found[product.ID.Hex()] = true
+		}
+
+		missing := []string{}
+
+		for _, id := range seeds {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+
+		return nil, &ProductsNotFoundError{IDs: missing}
+	}
+
+	if err := s.vectors.EnsureLoaded(); err != nil {
+		return nil, err
+	}
+
+	// Products written outside of the services are indexed now
+	for _, product := range products {
+		if _, ok := s.vectors.Get(product.ID.Hex()); !ok {
+			if err := s.vectors.Upsert(product); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	seedVectors := s.vectors.Vectors(seeds)
+
+	// surfacedBy lists, for each candidate, the seeds sharing a category or token with it;
+	// it can't score above zero against the others
+	surfacedBy := make(map[string][]string)
+
+	for _, seed := range seeds {
+		for _, id := range s.vectors.Candidates(seed) {
+			if merge && isSeed[id] {
+				continue
+			}
+			surfacedBy[id] = append(surfacedBy[id], seed)
+		}
+	}
+
+	ids := make([]string, 0, len(surfacedBy))
+
+	for id := range surfacedBy {
+		ids = append(ids, id)
+	}
+
+	candidates := s.vectors.Vectors(s.vectors.Visible(ids, opts))
+
+	perSeed := make(map[string][]ScoredCandidate, len(seeds))
+	merged := []ScoredCandidate{}
+	// closestSeed is the seed each merged candidate scored best against, which explains it
+	closestSeed := make(map[string]string)
+
+	for id, vector := range candidates {
+		var best float64
+
+		for _, seed := range surfacedBy[id] {
+			score := applyStockPenalty(s.scorer.Similarity(seedVectors[seed], vector), vector, opts)
+
+			if score <= 0 || score < opts.MinScore {
+				continue
+			}
+
+			perSeed[seed] = append(perSeed[seed], ScoredCandidate{ID: id, Score: score})
+
+			if score > best {
+				best = score
+				closestSeed[id] = seed
+			}
+		}
+
+		if best > 0 {
+			merged = append(merged, ScoredCandidate{ID: id, Score: best})
+		}
+	}
+
+	if merge {
+		ranked := s.rank(merged, candidates, opts)
+
+		recommendations, err := s.hydrate([][]ScoredCandidate{ranked}, opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.Explain {
+			for _, recommendation := range recommendations[0] {
+				id := recommendation.Product.ID.Hex()
+				recommendation.Explanation = s.scorer.Explain(seedVectors[closestSeed[id]], candidates[id])
+			}
+		}
+
+		return &BatchRecommendationResult{
+			Merged: &RecommendationResult{Recommendations: recommendations[0], Source: SourceContent},
+		}, nil
+	}
+
+	ranked := make([][]ScoredCandidate, len(seeds))
+
+	for i, seed := range seeds {
+		ranked[i] = s.rank(perSeed[seed], candidates, opts)
+	}
+
+	recommendations, err := s.hydrate(ranked, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*RecommendationResult, len(seeds))
+
+	for i, seed := range seeds {
+		if opts.Explain {
+			for _, recommendation := range recommendations[i] {
+				recommendation.Explanation = s.scorer.Explain(seedVectors[seed], candidates[recommendation.Product.ID.Hex()])
+			}
+		}
+
+		results[seed] = &RecommendationResult{Recommendations: recommendations[i], Source: SourceContent}
+	}
+
+	return &BatchRecommendationResult{Results: results}, nil
+}
+
+// rank sorts scored candidates and returns the requested page, diversified when the options ask for it
+func (s *batchRecommendationService) rank(scored []ScoredCandidate, candidates map[string]map[string]float64, opts RecommendationOptions) []ScoredCandidate {
+	if scored == nil {
+		return []ScoredCandidate{}
+	}
+
+	sortCandidates(scored)
+
+	return paginate(s.scorer.Diversify(scored, candidates, opts), opts)
+}
+
+// hydrate loads the products of several ranked lists with a single query, preserving each list's order.
+// Candidates whose product no longer exists, or the options can't recommend, are skipped.
+func (s *batchRecommendationService) hydrate(lists [][]ScoredCandidate, opts RecommendationOptions) ([][]*Recommendation, error) {
+	ids := []string{}
+	requested := make(map[string]bool)
+
+	for _, list := range lists {
+		for _, candidate := range list {
+			if !requested[candidate.ID] {
+				requested[candidate.ID] = true
+				ids = append(ids, candidate.ID)
+			}
+		}
+	}
+
+	products, err := s.repo.GetByIDs(ids, opts.productQuery())
+
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*entities.Product, len(products))
+
+	for _, product := range products {
+		byID[product.ID.Hex()] = product
+	}
+
+	hydrated := make([][]*Recommendation, len(lists))
+
+	for i, list := range lists {
+		hydrated[i] = make([]*Recommendation, 0, len(list))
+
+		for _, candidate := range list {
+			if product, ok := byID[candidate.ID]; ok {
+				hydrated[i] = append(hydrated[i], &Recommendation{Product: product, SimilarityScore: candidate.Score})
+			}
+		}
+	}
+
+	return hydrated, nil
+}
@@ -8,4 +8,6 @@ type CategoryService interface {
 	CreateCategory(category *entities.Category) error
 	UpdateCategory(category *entities.Category) error
 	DeleteCategory(id string) error
+	LoadCategoryTree() error
+	Watch(listener func())
 }
\ No newline at end of file
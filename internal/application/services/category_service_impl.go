@@ -3,14 +3,34 @@ package services
 import (
 	"backend-challenge/internal/domain/entities"
 	"backend-challenge/internal/domain/repositories"
+	"log"
+	"sync"
 )
 
 type categoryService struct {
-	repo repositories.CategoryRepository
+	repo        repositories.CategoryRepository
+	recommender *RecommendationService
+
+	mu        sync.Mutex
+	listeners []func()
+}
+
+// NewCategoryService builds the category service; the recommender is kept up to date with the category hierarchy
+func NewCategoryService(repo repositories.CategoryRepository, recommender *RecommendationService) CategoryService  {
+	return &categoryService{repo: repo, recommender: recommender}
 }
 
-func NewCategoryService(repo repositories.CategoryRepository) CategoryService  {
-	return &categoryService{repo: repo}
+// LoadCategoryTree reads the category hierarchy into the recommender
+func (s *categoryService) LoadCategoryTree() error {
+	categories, err := s.repo.GetAll()
+
+	if err != nil {
+		return err
+	}
+
+	s.recommender.SetCategoryTree(NewCategoryTree(categories))
+
+	return nil
 }
 
 func (s *categoryService) GetAllCategories() ([]*entities.Category, error) {
@@ -22,13 +42,56 @@ func (s *categoryService) GetCategoryByID(id string) (*entities.Category, error)
 }
 
 func (s *categoryService) CreateCategory(category *entities.Category) error {
-	return s.repo.Create(category)
+	if err := s.repo.Create(category); err != nil {
+		return err
+	}
+
+	s.refreshTree()
+
+	return nil
 }
 
 func (s *categoryService) UpdateCategory(category *entities.Category) error {
-	return s.repo.Update(category)
+	if err := s.repo.Update(category); err != nil {
+		return err
+	}
+
+	s.refreshTree()
+
+	return nil
 }
 
 func (s *categoryService) DeleteCategory(id string) error {
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.refreshTree()
+
+	return nil
+}
+
+// Watch registers a listener told every time the hierarchy changes, e.g. to drop cached recommendations
+func (s *categoryService) Watch(listener func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// refreshTree reloads the category hierarchy after a write. A failure only leaves the previous
+// hierarchy in place, so the write itself is not failed.
+func (s *categoryService) refreshTree() {
+	if err := s.LoadCategoryTree(); err != nil {
+		log.Printf("Error refreshing category tree: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
 }
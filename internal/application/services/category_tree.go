@@ -0,0 +1,157 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"log"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// categoryCredit is the category feature of a product's own categories, which only they get in full
+const categoryCredit = 1.0
+
+// ancestorCredit is the share of a category feature given to its parent, halving with every level up,
+// so products in sibling subcategories are partially similar
+const ancestorCredit = 0.5
+
+// CategoryTree resolves the categories of products, which are names, against the category hierarchy.
+// Subcategories may reference their children by ID, by the store platform's ID or by name, which need not be
+// a category of its own. A category listed under several parents keeps the first one.
+type CategoryTree struct {
+	parents  map[string]string   // lowercased category name -> parent's
+	children map[string][]string // lowercased category name -> children's
+}
+
+func NewCategoryTree(categories []*entities.Category) *CategoryTree {
+	tree := &CategoryTree{
+		parents:  make(map[string]string),
+		children: make(map[string][]string),
+	}
+
+	names := make(map[string]string, 3*len(categories))
+
+	for _, category := range categories {
+		name := strings.ToLower(category.Name)
+		names[category.ID.Hex()] = name
+		names[name] = name
+
+		if category.ExternalID != "" {
+			names[category.ExternalID] = name
+		}
+	}
+
+	for _, category := range categories {
+		parent := strings.ToLower(category.Name)
+
+		for _, reference := range category.Subcategories {
+			child, ok := names[strings.ToLower(reference)]
+
+			// Products can be in categories without a document of their own, but unknown IDs can't be named
+			if !ok && isCategoryID(reference) {
+				log.Printf("Category %s references unknown subcategory %s, leaving it out", category.Name, reference)
+				continue
+			}
+
+			if !ok {
+				child = strings.ToLower(reference)
+			}
+
+			if child == parent {
+				continue
+			}
+
+			if _, ok := tree.parents[child]; ok {
+				continue
+			}
+
+			tree.parents[child] = parent
+			tree.children[parent] = append(tree.children[parent], child)
+		}
+	}
+
+	return tree
+}
+
+// isCategoryID tells whether a subcategory reference is an ID, ours or the store platform's numeric one, rather than a name
+func isCategoryID(reference string) bool {
+	if primitive.IsValidObjectID(reference) {
+		return true
+	}
+
+	_, err := strconv.ParseUint(reference, 10, 64)
+
+	return err == nil
+}
+
+// Ancestors returns the ancestors of a category, the closest first
+func (t *CategoryTree) Ancestors(category string) []string {
+	if t == nil {
+		return nil
+	}
+
+	ancestors := []string{}
+	seen := map[string]bool{category: true}
+
+	// A misconfigured hierarchy may loop, stop at the first category seen twice
+	for parent, ok := t.parents[category]; ok && !seen[parent]; parent, ok = t.parents[parent] {
+		ancestors = append(ancestors, parent)
+		seen[parent] = true
+	}
+
+	return ancestors
+}
+
+// Related returns the categories whose products share the category's parent or the category itself
+// with its products: its descendants, its siblings and theirs
+func (t *CategoryTree) Related(category string) []string {
+	if t == nil {
+		return nil
+	}
+
+	root, ok := t.parents[category]
+
+	if !ok {
+		root = category
+	}
+
+	related := []string{}
+	seen := map[string]bool{root: true}
+	pending := append([]string{}, t.children[root]...)
+
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		if seen[current] {
+			continue
+		}
+
+		seen[current] = true
+		pending = append(pending, t.children[current]...)
+
+		if current != category {
+			related = append(related, current)
+		}
+	}
+
+	return related
+}
+
+// ancestorFeatures returns the category features a product gets from the ancestors of its categories,
+// worth ancestorCredit for a parent, its square for a grandparent and so on
+func (t *CategoryTree) ancestorFeatures(categories []string) map[string]float64 {
+	features := make(map[string]float64)
+
+	for _, category := range categories {
+		credit := categoryCredit
+
+		for _, ancestor := range t.Ancestors(category) {
+			credit *= ancestorCredit
+			features["category_"+ancestor] = max(features["category_"+ancestor], credit)
+		}
+	}
+
+	return features
+}
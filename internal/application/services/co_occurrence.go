@@ -0,0 +1,141 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"sort"
+	"time"
+)
+
+// CoOccurrenceConfig tunes the item to item similarities built from events
+type CoOccurrenceConfig struct {
+	MinCoOccurrences int `json:"minCoOccurrences"` // orders or sessions two products must share to be related
+	MaxNeighbors     int `json:"maxNeighbors"`     // related products kept per product
+}
+
+func DefaultCoOccurrenceConfig() CoOccurrenceConfig {
+	return CoOccurrenceConfig{
+		MinCoOccurrences: 2,
+		MaxNeighbors:     50,
+	}
+}
+
+// coOccurrenceEventTypes are the events whose baskets say two products go together
+var coOccurrenceEventTypes = []entities.EventType{entities.EventPurchase, entities.EventView}
+
+// CoOccurrenceService builds the "also bought" model: how often products share an order or session
+type CoOccurrenceService struct {
+	events       repositories.EventRepository
+	similarities repositories.ItemSimilarityRepository
+	config       CoOccurrenceConfig
+}
+
+func NewCoOccurrenceService(events repositories.EventRepository, similarities repositories.ItemSimilarityRepository, config CoOccurrenceConfig) *CoOccurrenceService {
+	return &CoOccurrenceService{events: events, similarities: similarities, config: config}
+}
+
+// Rebuild recomputes the item similarities from the whole event log and stores them,
+// returning how many products have related products
+func (s *CoOccurrenceService) Rebuild() (int, error) {
+	events, err := s.events.GetByTypes(coOccurrenceEventTypes)
+
+	if err != nil {
+		return 0, err
+	}
+
+	similarities := BuildItemSimilarities(events, s.config)
+
+	if err := s.similarities.ReplaceAll(similarities); err != nil {
+		return 0, err
+	}
+
+	return len(similarities), nil
+}
+
+// BuildItemSimilarities relates the products appearing in the same baskets (orders, or sessions without one).
+// Two products score the Jaccard index of their baskets: the baskets with both over the baskets with either.
+func BuildItemSimilarities(events []*entities.Event, config CoOccurrenceConfig) []*entities.ItemSimilarity {
+	baskets := make(map[string]map[string]bool)
+
+	for _, event := range events {
+		basket := event.Basket()
+
+		// Anonymous events can't be related to any other
+		if basket == "" {
+			continue
+		}
+
+		if baskets[basket] == nil {
+			baskets[basket] = make(map[string]bool)
+		}
+
+		baskets[basket][event.ProductID] = true
+	}
+
+	occurrences := make(map[string]int)
+	pairs := make(map[string]map[string]int)
+
+	for _, products := range baskets {
+		for product := range products {
+			occurrences[product]++
+
+			for other := range products {
+				if other == product {
+					continue
+				}
+
+				if pairs[product] == nil {
+					pairs[product] = make(map[string]int)
+				}
+
+				pairs[product][other]++
+			}
+		}
+	}
+
+	now := time.Now()
+	similarities := []*entities.ItemSimilarity{}
+
+	for product, others := range pairs {
+		neighbors := []entities.ItemNeighbor{}
+
+		for other, both := range others {
+			if both < config.MinCoOccurrences {
+				continue
+			}
+
+			neighbors = append(neighbors, entities.ItemNeighbor{
+				ProductID:     other,
+				Score:         float64(both) / float64(occurrences[product]+occurrences[other]-both),
+				CoOccurrences: both,
+			})
+		}
+
+		if len(neighbors) == 0 {
+			continue
+		}
+
+		sort.Slice(neighbors, func(i, j int) bool {
+			if neighbors[i].Score != neighbors[j].Score {
+				return neighbors[i].Score > neighbors[j].Score
+			}
+			return neighbors[i].ProductID < neighbors[j].ProductID
+		})
+
+		if config.MaxNeighbors > 0 && len(neighbors) > config.MaxNeighbors {
+			neighbors = neighbors[:config.MaxNeighbors]
+		}
+
+		similarities = append(similarities, &entities.ItemSimilarity{
+			ProductID: product,
+			Neighbors: neighbors,
+			UpdatedAt: now,
+		})
+	}
+
+	sort.Slice(similarities, func(i, j int) bool {
+		return similarities[i].ProductID < similarities[j].ProductID
+	})
+
+	return similarities
+}
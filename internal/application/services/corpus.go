@@ -0,0 +1,200 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"math"
+	"sort"
+)
+
+// catalogPrices is the key of the price list holding every product, regardless of category
+const catalogPrices = ""
+
+// corpusStats holds the catalog wide statistics used to weight product vectors: document frequencies
+// for IDF, grouped by language, prices for category relative price bands and counts for popularity percentiles.
+type corpusStats struct {
+	documents   map[string]int            // lang -> number of products with text in that language
+	frequencies map[string]map[string]int // lang -> term key -> number of products containing it
+	prices      map[string][]float64      // category -> sorted prices of its products
+	clicks      []float64                 // sorted click counts of every product
+	sales       []float64                 // sorted sold counts of every product
+}
+
+func newCorpusStats() *corpusStats {
+	return &corpusStats{
+		documents:   make(map[string]int),
+		frequencies: make(map[string]map[string]int),
+		prices:      make(map[string][]float64),
+	}
+}
+
+// add counts the terms, price and popularity of a product vector into the corpus
+func (c *corpusStats) add(vector *entities.ProductVector) {
+	if vector.Price > 0 {
+		for _, category := range append([]string{catalogPrices}, vector.Categories...) {
+			c.prices[category] = insertSorted(c.prices[category], vector.Price)
+		}
+	}
+
+	c.clicks = insertSorted(c.clicks, float64(vector.ClickCount))
+	c.sales = insertSorted(c.sales, float64(vector.SoldCount))
+
+	for lang, terms := range vector.Terms {
+		c.documents[lang]++
+
+		if c.frequencies[lang] == nil {
+			c.frequencies[lang] = make(map[string]int)
+		}
+
+		for key := range terms {
+			c.frequencies[lang][key]++
+		}
+	}
+}
+
+// remove reverts a previous add of the same product vector
+func (c *corpusStats) remove(vector *entities.ProductVector) {
+	if vector.Price > 0 {
+		for _, category := range append([]string{catalogPrices}, vector.Categories...) {
+			if prices := removeSorted(c.prices[category], vector.Price); len(prices) > 0 {
+				c.prices[category] = prices
+			} else {
+				delete(c.prices, category)
+			}
+		}
+	}
+
+	c.clicks = removeSorted(c.clicks, float64(vector.ClickCount))
+	c.sales = removeSorted(c.sales, float64(vector.SoldCount))
+
+	for lang, terms := range vector.Terms {
+		c.documents[lang]--
+
+		for key := range terms {
+			c.frequencies[lang][key]--
+
+			if c.frequencies[lang][key] <= 0 {
+				delete(c.frequencies[lang], key)
+			}
+		}
+
+		if c.documents[lang] <= 0 {
+			delete(c.documents, lang)
+			delete(c.frequencies, lang)
+		}
+	}
+}
+
+// recount moves a product's popularity counts from the previous vector's to the updated one's
+func (c *corpusStats) recount(previous, updated *entities.ProductVector) {
+	c.clicks = insertSorted(removeSorted(c.clicks, float64(previous.ClickCount)), float64(updated.ClickCount))
+	c.sales = insertSorted(removeSorted(c.sales, float64(previous.SoldCount)), float64(updated.SoldCount))
+}
+
+// inverseDocumentFrequency returns the smoothed IDF of a term for a language.
+// Languages without documents weigh every term as 1, which falls back to raw term counts.
+func (c *corpusStats) inverseDocumentFrequency(lang, key string) float64 {
+	if c == nil || c.documents[lang] == 0 {
+		return 1.0
+	}
+
+	documents := float64(c.documents[lang])
+	frequency := float64(c.frequencies[lang][key])
+
+	return math.Log((1+documents)/(1+frequency)) + 1
+}
+
+// medianPrice returns the typical price of the given categories: the mean of their medians.
+// Categories without prices fall back to the catalog median, and 0 means nothing is known.
+func (c *corpusStats) medianPrice(categories []string) float64 {
+	if c == nil {
+		return 0
+	}
+
+	var sum float64
+	var count int
+
+	for _, category := range categories {
+		if prices := c.prices[category]; len(prices) > 0 {
+			sum += median(prices)
+			count++
+		}
+	}
+
+	if count > 0 {
+		return sum / float64(count)
+	}
+
+	if prices := c.prices[catalogPrices]; len(prices) > 0 {
+		return median(prices)
+	}
+
+	return 0
+}
+
+// median returns the middle value of a sorted, non empty slice
+func median(sorted []float64) float64 {
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// clickRank and salesRank place a product's counts within the catalog, from 0 (least popular) to 1
+func (c *corpusStats) clickRank(clicks int) float64 {
+	if c == nil || len(c.clicks) == 0 {
+		return logScale(float64(clicks))
+	}
+
+	return percentileRank(c.clicks, float64(clicks))
+}
+
+func (c *corpusStats) salesRank(sales int) float64 {
+	if c == nil || len(c.sales) == 0 {
+		return logScale(float64(sales))
+	}
+
+	return percentileRank(c.sales, float64(sales))
+}
+
+// percentileRank returns the share of values below the given one, counting ties as half
+func percentileRank(sorted []float64, value float64) float64 {
+	below := sort.SearchFloat64s(sorted, value)
+	above := sort.Search(len(sorted), func(i int) bool { return sorted[i] > value })
+
+	return (float64(below) + float64(above-below)/2) / float64(len(sorted))
+}
+
+// logScale maps a count to a range of 0 to 1 without a catalog to compare against.
+// Each tenfold increase still moves the value, unlike a sigmoid that saturates after a handful.
+func logScale(value float64) float64 {
+	if value <= 0 {
+		return 0
+	}
+
+	return 1 - 1/(1+math.Log10(1+value))
+}
+
+// insertSorted adds a value to a sorted slice, keeping it sorted
+func insertSorted(sorted []float64, value float64) []float64 {
+	position := sort.SearchFloat64s(sorted, value)
+
+	sorted = append(sorted, 0)
+	copy(sorted[position+1:], sorted[position:])
+	sorted[position] = value
+
+	return sorted
+}
+
+// removeSorted removes one occurrence of a value from a sorted slice, if present
+func removeSorted(sorted []float64, value float64) []float64 {
+	position := sort.SearchFloat64s(sorted, value)
+
+	if position < len(sorted) && sorted[position] == value {
+		sorted = append(sorted[:position], sorted[position+1:]...)
+	}
+
+	return sorted
+}
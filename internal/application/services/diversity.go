@@ -0,0 +1,125 @@
+package services
+
+import "strings"
+
+const (
+	// DefaultDiversityLambda balances relevance and variety when a request diversifies without a lambda
+	DefaultDiversityLambda = 0.7
+	// maxDiversityPool is how many of the best candidates are re-ranked; the rest keep their order after them
+	maxDiversityPool = 200
+)
+
+// Diversify re-ranks candidates sorted by score with Maximal Marginal Relevance:
+// each pick maximizes lambda * relevance - (1 - lambda) * its highest similarity to the products already picked.
+// Candidates over the per category limit of the options are dropped. Scores are left untouched.
+func (s *RecommendationService) Diversify(scored []ScoredCandidate, vectors map[string]map[string]float64, opts RecommendationOptions) []ScoredCandidate {
+	if !opts.Diversify && opts.MaxPerCategory <= 0 {
+		return scored
+	}
+
+	pool := scored
+	var rest []ScoredCandidate
+
+	if len(pool) > maxDiversityPool {
+		pool, rest = scored[:maxDiversityPool], scored[maxDiversityPool:]
+	}
+
+	// Only as many picks as the requested page needs
+	picks := len(pool)
+
+	if opts.Limit > 0 {
+		picks = min(picks, opts.Offset+opts.Limit)
+	}
+
+	remaining := append([]ScoredCandidate{}, pool...)
+	// redundancy holds each remaining candidate's highest similarity to the picked ones
+	redundancy := make([]float64, len(remaining))
+	perCategory := make(map[string]int)
+
+	selected := make([]ScoredCandidate, 0, picks)
+
+	for len(selected) < picks && len(remaining) > 0 {
+		best := -1
+		var bestValue float64
+
+		for i, candidate := range remaining {
+			if exceedsCategoryLimit(vectors[candidate.ID], perCategory, opts.MaxPerCategory) {
+				continue
+			}
+
+			value := candidate.Score
+
+			if opts.Diversify {
+				value = opts.Lambda*candidate.Score - (1-opts.Lambda)*redundancy[i]
+			}
+
+			if best == -1 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		if best == -1 { // every remaining candidate is over its category limit
+			break
+		}
+
+		picked := remaining[best]
+		selected = append(selected, picked)
+
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		redundancy = append(redundancy[:best], redundancy[best+1:]...)
+
+		for category := range vectorCategories(vectors[picked.ID]) {
+			perCategory[category]++
+		}
+
+		if opts.Diversify {
+			for i, candidate := range remaining {
+				redundancy[i] = max(redundancy[i], s.Similarity(vectors[candidate.ID], vectors[picked.ID]))
+			}
+		}
+	}
+
+	// Past the requested page the order doesn't matter, but results can still be paginated further
+	for _, candidate := range append(remaining, rest...) {
+		if exceedsCategoryLimit(vectors[candidate.ID], perCategory, opts.MaxPerCategory) {
+			continue
+		}
+
+		selected = append(selected, candidate)
+
+		for category := range vectorCategories(vectors[candidate.ID]) {
+			perCategory[category]++
+		}
+	}
+
+	return selected
+}
+
+// exceedsCategoryLimit reports whether picking a product would go over the limit of any of its categories
+func exceedsCategoryLimit(vector map[string]float64, perCategory map[string]int, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+
+	for category := range vectorCategories(vector) {
+		if perCategory[category] >= limit {
+			return true
+		}
+	}
+
+	return false
+}
+
+// vectorCategories returns the categories a feature vector was built from.
+// The ancestors the vector gets partial credit for are left out, or sibling subcategories would share a limit.
+func vectorCategories(vector map[string]float64) map[string]bool {
+	categories := make(map[string]bool)
+
+	for key, value := range vector {
+		if strings.HasPrefix(key, "category_") && value >= categoryCredit {
+			categories[strings.TrimPrefix(key, "category_")] = true
+		}
+	}
+
+	return categories
+}
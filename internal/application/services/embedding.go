@@ -0,0 +1,215 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// EmbeddingConfig tunes the dense product embeddings and their nearest neighbor index
+type EmbeddingConfig struct {
+	Dimensions           int `json:"dimensions"`           // latent dimensions kept by the model
+	MinDocumentFrequency int `json:"minDocumentFrequency"` // products a term must appear in to be learned
+	M                    int `json:"m"`                    // neighbors linked per node in the index
+	EfConstruction       int `json:"efConstruction"`       // candidates considered when linking a new node
+	EfSearch             int `json:"efSearch"`             // candidates considered when searching
+}
+
+func DefaultEmbeddingConfig() EmbeddingConfig {
+	return EmbeddingConfig{
+		Dimensions:           64,
+		MinDocumentFrequency: 2,
+		M:                    16,
+		EfConstruction:       200,
+		EfSearch:             100,
+	}
+}
+
+const (
+	// embeddingOversampling and embeddingPowerIterations trade training time for a more accurate decomposition
+	embeddingOversampling    = 10
+	embeddingPowerIterations = 3
+	// embeddingSeed makes training reproducible
+	embeddingSeed = 42
+)
+
+// EmbeddingModel maps text terms and categories to dense vectors learned with latent semantic analysis:
+// a truncated SVD of the catalog's TF-IDF matrix. Terms used in the same kind of products, like
+// "linterna" and "farol", end up close, so products can be similar without sharing a single term.
+type EmbeddingModel struct {
+	terms      map[string]*entities.TermEmbedding
+	dimensions int
+}
+
+func NewEmbeddingModel(terms []*entities.TermEmbedding) *EmbeddingModel {
+	model := &EmbeddingModel{terms: make(map[string]*entities.TermEmbedding, len(terms))}
+
+	for _, term := range terms {
+		model.terms[term.Term] = term
+		model.dimensions = len(term.Vector)
+	}
+
+	return model
+}
+
+// TrainEmbeddingModel learns the term vectors from the catalog's product vectors
+func TrainEmbeddingModel(vectors []*entities.ProductVector, config EmbeddingConfig) *EmbeddingModel {
+	documents := make([]map[string]float64, 0, len(vectors))
+	frequencies := make(map[string]int)
+
+	for _, vector := range vectors {
+		terms := embeddingTerms(vector)
+		documents = append(documents, terms)
+
+		for term := range terms {
+			frequencies[term]++
+		}
+	}
+
+	// Terms seen in a single product say nothing about how products relate
+	vocabulary := make([]string, 0, len(frequencies))
+
+	for term, frequency := range frequencies {
+		if frequency >= config.MinDocumentFrequency {
+			vocabulary = append(vocabulary, term)
+		}
+	}
+
+	sort.Strings(vocabulary)
+
+	columns := make(map[string]int, len(vocabulary))
+	idf := make([]float64, len(vocabulary))
+
+	for i, term := range vocabulary {
+		columns[term] = i
+		idf[i] = math.Log(float64(len(documents))/float64(frequencies[term])) + 1
+	}
+
+	rows := make([]sparseRow, 0, len(documents))
+
+	for _, terms := range documents {
+		row := sparseRow{}
+
+		for term, count := range terms {
+			if column, ok := columns[term]; ok {
+				row.columns = append(row.columns, column)
+				row.values = append(row.values, termWeight(count, idf[column]))
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	v, _ := truncatedSVD(rows, len(vocabulary), config.Dimensions, embeddingOversampling, embeddingPowerIterations, rand.New(rand.NewSource(embeddingSeed)))
+
+	terms := make([]*entities.TermEmbedding, 0, len(vocabulary))
+
+	for i, term := range vocabulary {
+		terms = append(terms, &entities.TermEmbedding{
+			Term:    term,
+			Version: productVectorVersion,
+			IDF:     idf[i],
+			Vector:  v[i],
+		})
+	}
+
+	return NewEmbeddingModel(terms)
+}
+
+// Terms returns the learned term vectors, to be persisted
+func (m *EmbeddingModel) Terms() []*entities.TermEmbedding {
+	terms := make([]*entities.TermEmbedding, 0, len(m.terms))
+
+	for _, term := range m.terms {
+		terms = append(terms, term)
+	}
+
+	sort.Slice(terms, func(i, j int) bool { return terms[i].Term < terms[j].Term })
+
+	return terms
+}
+
+// Current tells whether the model was trained on product vectors like the ones built now
+func (m *EmbeddingModel) Current() bool {
+	for _, term := range m.terms {
+		return term.Version == productVectorVersion
+	}
+
+	return false
+}
+
+// Embed folds a product into the latent space: the sum of its terms' vectors weighted by TF-IDF,
+// normalized so the dot product of two embeddings is their cosine similarity.
+// Products without any known term have no embedding.
+func (m *EmbeddingModel) Embed(vector *entities.ProductVector) []float64 {
+	embedding := make([]float64, m.dimensions)
+	known := false
+
+	for key, count := range embeddingTerms(vector) {
+		term, ok := m.terms[key]
+
+		if !ok {
+			continue
+		}
+
+		known = true
+		weight := termWeight(count, term.IDF)
+
+		for i, value := range term.Vector {
+			embedding[i] += weight * value
+		}
+	}
+
+	if !known || !normalizeEmbedding(embedding) {
+		return nil
+	}
+
+	return embedding
+}
+
+// embeddingTerms counts the stems of a product's name and description, whatever the field or language,
+// along with its categories
+func embeddingTerms(vector *entities.ProductVector) map[string]float64 {
+	terms := make(map[string]float64)
+
+	for _, counts := range vector.Terms {
+		for key, count := range counts {
+			stem := strings.TrimPrefix(strings.TrimPrefix(key, "name_"), "description_")
+			terms[stem] += count
+		}
+	}
+
+	for _, category := range vector.Categories {
+		terms["category_"+category]++
+	}
+
+	return terms
+}
+
+// termWeight dampens repeated terms logarithmically and scales them by their rarity
+func termWeight(count, idf float64) float64 {
+	return (1 + math.Log(count)) * idf
+}
+
+// normalizeEmbedding scales a vector to unit length in place, reporting false for the zero vector
+func normalizeEmbedding(embedding []float64) bool {
+	var norm float64
+
+	for _, value := range embedding {
+		norm += value * value
+	}
+
+	if norm == 0 {
+		return false
+	}
+
+	norm = math.Sqrt(norm)
+
+	for i := range embedding {
+		embedding[i] /= norm
+	}
+
+	return true
+}
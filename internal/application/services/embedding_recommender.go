@@ -0,0 +1,193 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"log"
+	"sync"
+)
+
+// maxEmbeddingCandidates is how many nearest neighbors are ranked, leaving room for filters and diversity
+const maxEmbeddingCandidates = 200
+
+// EmbeddingIndex keeps a dense embedding of every product in an HNSW graph, following the VectorStore.
+// The model is trained offline by cmd/embeddings; when none is stored, or it was trained on outdated
+// product vectors, one is trained from the catalog on load.
+type EmbeddingIndex struct {
+	repo    repositories.TermEmbeddingRepository
+	vectors *VectorStore
+	config  EmbeddingConfig
+
+	mu         sync.RWMutex
+	loaded     bool
+	model      *EmbeddingModel
+	embeddings map[string][]float64
+	graph      *HNSW
+}
+
+func NewEmbeddingIndex(repo repositories.TermEmbeddingRepository, vectors *VectorStore, config EmbeddingConfig) *EmbeddingIndex {
+	return &EmbeddingIndex{
+		repo:       repo,
+		vectors:    vectors,
+		config:     config,
+		embeddings: make(map[string][]float64),
+		graph:      NewHNSW(config.M, config.EfConstruction),
+	}
+}
+
+// Load reads the model, embeds every product and indexes them, then follows the product writes
+func (e *EmbeddingIndex) Load() error {
+	if err := e.vectors.EnsureLoaded(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.loaded {
+		return nil
+	}
+
+	terms, err := e.repo.GetAll()
+
+	if err != nil {
+		return err
+	}
+
+	// Changes made while loading wait for the lock, then apply on top
+	vectors := e.vectors.Watch(e.update)
+
+	model := NewEmbeddingModel(terms)
+
+	if !model.Current() {
+		log.Printf("No current embedding model found, training one from %d products", len(vectors))
+
+		model = TrainEmbeddingModel(vectors, e.config)
+
+		// The model works from memory all the same, it will be trained again on the next start
+		if err := e.repo.ReplaceAll(model.Terms()); err != nil {
+			log.Printf("Error storing embedding model: %v", err)
+		}
+	}
+
+	e.model = model
+
+	for _, vector := range vectors {
+		e.updateLocked(vector.ProductID.Hex(), vector)
+	}
+
+	e.loaded = true
+
+	log.Printf("Indexed %d product embeddings", e.graph.Len())
+
+	return nil
+}
+
+// EnsureLoaded loads the index on first use
+func (e *EmbeddingIndex) EnsureLoaded() error {
+	e.mu.RLock()
+	loaded := e.loaded
+	e.mu.RUnlock()
+
+	if loaded {
+		return nil
+	}
+
+	return e.Load()
+}
+
+// Get returns the embedding of a product
+func (e *EmbeddingIndex) Get(productID string) ([]float64, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	embedding, ok := e.embeddings[productID]
+	return embedding, ok
+}
+
+// Nearest returns up to k products with the most similar embeddings, best first
+func (e *EmbeddingIndex) Nearest(embedding []float64, k int) []ScoredCandidate {
+	return e.graph.Search(embedding, k, e.config.EfSearch)
+}
+
+func (e *EmbeddingIndex) update(productID string, vector *entities.ProductVector) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.updateLocked(productID, vector)
+}
+
+func (e *EmbeddingIndex) updateLocked(productID string, vector *entities.ProductVector) {
+	var embedding []float64
+
+	if vector != nil && e.model != nil {
+		embedding = e.model.Embed(vector)
+	}
+
+	// Products without any known term can't be placed in the latent space
+	if embedding == nil {
+		delete(e.embeddings, productID)
+		e.graph.Remove(productID)
+		return
+	}
+
+	e.embeddings[productID] = embedding
+	e.graph.Add(productID, embedding)
+}
+
+// embeddingRecommender recommends the products whose dense embeddings are nearest, by cosine similarity
+type embeddingRecommender struct {
+	index   *EmbeddingIndex
+	vectors *VectorStore
+	scorer  *RecommendationService
+}
+
+func NewEmbeddingRecommender(index *EmbeddingIndex, vectors *VectorStore, scorer *RecommendationService) Recommender {
+	return &embeddingRecommender{index: index, vectors: vectors, scorer: scorer}
+}
+
+func (r *embeddingRecommender) Recommend(productID string, opts RecommendationOptions) ([]ScoredCandidate, error) {
+	if err := r.index.EnsureLoaded(); err != nil {
+		return nil, err
+	}
+
+	embedding, ok := r.index.Get(productID)
+
+	if !ok {
+		return []ScoredCandidate{}, nil
+	}
+
+	// The product itself is its own nearest neighbor
+	nearest := r.index.Nearest(embedding, maxEmbeddingCandidates+1)
+
+	ids := make([]string, 0, len(nearest))
+
+	for _, candidate := range nearest {
+		ids = append(ids, candidate.ID)
+	}
+
+	// Diversity and stock are judged on the sparse vectors, which also tell the unpublished products apart
+	candidates := r.vectors.Vectors(r.vectors.Visible(ids, opts))
+
+	scored := make([]ScoredCandidate, 0, len(nearest))
+
+	for _, candidate := range nearest {
+		vector, ok := candidates[candidate.ID]
+
+		if candidate.ID == productID || !ok {
+			continue
+		}
+
+		score := applyStockPenalty(candidate.Score, vector, opts)
+
+		if score <= 0 || score < opts.MinScore {
+			continue
+		}
+
+		scored = append(scored, ScoredCandidate{ID: candidate.ID, Score: score})
+	}
+
+	sortCandidates(scored)
+
+	return paginate(r.scorer.Diversify(scored, candidates, opts), opts), nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"math"
+	"sort"
+)
+
+// EvaluationQuery is a product held out of an interaction log along with the products that went with it,
+// which are the recommendations a good strategy should make for it
+type EvaluationQuery struct {
+	Seed     string
+	Relevant []string
+}
+
+// StrategyMetrics are the offline quality metrics of a strategy, averaged over the evaluation queries
+type StrategyMetrics struct {
+	Precision float64 `json:"precision"` // share of the top k recommendations that are relevant
+	Recall    float64 `json:"recall"`    // share of the relevant products found in the top k
+	NDCG      float64 `json:"ndcg"`      // like recall, but relevant products count more the higher they rank
+	Coverage  float64 `json:"coverage"`  // share of the catalog recommended for at least one query
+	Diversity float64 `json:"diversity"` // average dissimilarity between the products recommended together
+}
+
+// EvaluationReport is the outcome of evaluating every strategy on the same queries
+type EvaluationReport struct {
+	K          int                        `json:"k"`
+	Products   int                        `json:"products"`
+	Queries    int                        `json:"queries"` // queries whose seed and relevant products are in the catalog
+	Strategies map[string]StrategyMetrics `json:"strategies"`
+}
+
+// EvaluationQueries turns held out interactions into leave one out queries: every product of a basket
+// (an order, or a session or user without one) is a seed whose relevant products are the rest of the basket.
+// Queries are sorted so reports are reproducible.
+func EvaluationQueries(events []*entities.Event) []EvaluationQuery {
+	baskets := make(map[string][]string)
+	seen := make(map[string]map[string]bool)
+
+	for _, event := range events {
+		basket := event.Basket()
+
+		if basket == "" {
+			continue
+		}
+
+		if seen[basket] == nil {
+			seen[basket] = make(map[string]bool)
+		}
+
+		if !seen[basket][event.ProductID] {
+			seen[basket][event.ProductID] = true
+			baskets[basket] = append(baskets[basket], event.ProductID)
+		}
+	}
+
+	keys := make([]string, 0, len(baskets))
+
+	for key := range baskets {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	queries := []EvaluationQuery{}
+
+	for _, key := range keys {
+		products := baskets[key]
+
+		if len(products) < 2 {
+			continue
+		}
+
+		for i, seed := range products {
+			relevant := make([]string, 0, len(products)-1)
+			relevant = append(relevant, products[:i]...)
+			relevant = append(relevant, products[i+1:]...)
+
+			queries = append(queries, EvaluationQuery{Seed: seed, Relevant: relevant})
+		}
+	}
+
+	return queries
+}
+
+// Evaluator measures how well each recommendation strategy predicts held out interactions
+type Evaluator struct {
+	vectors      *VectorStore
+	scorer       *RecommendationService
+	recommenders map[string]Recommender
+}
+
+func NewEvaluator(vectors *VectorStore, scorer *RecommendationService, recommenders map[string]Recommender) *Evaluator {
+	return &Evaluator{vectors: vectors, scorer: scorer, recommenders: recommenders}
+}
+
+// Evaluate asks every strategy for the top k recommendations of each query's seed and scores them.
+// Queries whose seed isn't in the catalog are skipped, and so are relevant products that aren't.
+func (e *Evaluator) Evaluate(queries []EvaluationQuery, k int) (*EvaluationReport, error) {
+	if err := e.vectors.EnsureLoaded(); err != nil {
+		return nil, err
+	}
+
+	catalog := len(e.vectors.IDs())
+	valid := e.validQueries(queries)
+
+	report := &EvaluationReport{
+		K:          k,
+		Products:   catalog,
+		Queries:    len(valid),
+		Strategies: make(map[string]StrategyMetrics, len(e.recommenders)),
+	}
+
+	opts := DefaultRecommendationOptions()
+	opts.Limit = k
+	// Held out interactions may involve products unpublished since, so the whole catalog is ranked
+	opts.IncludeUnpublished = true
+
+	for strategy, recommender := range e.recommenders {
+		var metrics StrategyMetrics
+		var diverseLists int
+		recommended := make(map[string]bool)
+
+		for _, query := range valid {
+			ranked, err := recommender.Recommend(query.Seed, opts)
+
+			if err != nil {
+				return nil, err
+			}
+
+			ids := make([]string, 0, len(ranked))
+
+			for _, candidate := range ranked {
+				ids = append(ids, candidate.ID)
+				recommended[candidate.ID] = true
+			}
+
+			precision, recall, ndcg := rankingMetrics(ids, query.Relevant, k)
+
+			metrics.Precision += precision
+			metrics.Recall += recall
+			metrics.NDCG += ndcg
+
+			if diversity, ok := e.diversity(ids); ok {
+				metrics.Diversity += diversity
+				diverseLists++
+			}
+		}
+
+		if len(valid) > 0 {
+			metrics.Precision /= float64(len(valid))
+			metrics.Recall /= float64(len(valid))
+			metrics.NDCG /= float64(len(valid))
+		}
+
+		if diverseLists > 0 {
+			metrics.Diversity /= float64(diverseLists)
+		}
+
+		if catalog > 0 {
+			metrics.Coverage = float64(len(recommended)) / float64(catalog)
+		}
+
+		report.Strategies[strategy] = StrategyMetrics{
+			Precision: roundMetric(metrics.Precision),
+			Recall:    roundMetric(metrics.Recall),
+			NDCG:      roundMetric(metrics.NDCG),
+			Coverage:  roundMetric(metrics.Coverage),
+			Diversity: roundMetric(metrics.Diversity),
+		}
+	}
+
+	return report, nil
+}
+
+// validQueries drops the products that aren't in the catalog, and the queries left without a seed or relevant products
+func (e *Evaluator) validQueries(queries []EvaluationQuery) []EvaluationQuery {
+	valid := []EvaluationQuery{}
+
+	for _, query := range queries {
+		if _, ok := e.vectors.Get(query.Seed); !ok {
+			continue
+		}
+
+		known := e.vectors.Vectors(query.Relevant)
+		relevant := make([]string, 0, len(known))
+
+		for _, id := range query.Relevant {
+			if _, ok := known[id]; ok {
+				relevant = append(relevant, id)
+			}
+		}
+
+		if len(relevant) > 0 {
+			valid = append(valid, EvaluationQuery{Seed: query.Seed, Relevant: relevant})
+		}
+	}
+
+	return valid
+}
+
+// diversity is the average dissimilarity of every pair of recommended products; lists of one product have none
+func (e *Evaluator) diversity(ids []string) (float64, bool) {
+	if len(ids) < 2 {
+		return 0, false
+	}
+
+	vectors := e.vectors.Vectors(ids)
+
+	var total float64
+	var pairs int
+
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			total += 1 - e.scorer.Similarity(vectors[ids[i]], vectors[ids[j]])
+			pairs++
+		}
+	}
+
+	return total / float64(pairs), true
+}
+
+// rankingMetrics computes precision, recall and NDCG at k of a ranked list against binary relevance
+func rankingMetrics(ranked, relevant []string, k int) (precision, recall, ndcg float64) {
+	if k <= 0 || len(relevant) == 0 {
+		return 0, 0, 0
+	}
+
+	isRelevant := make(map[string]bool, len(relevant))
+
+	for _, id := range relevant {
+		isRelevant[id] = true
+	}
+
+	var hits int
+	var dcg, idcg float64
+
+	for i, id := range ranked {
+		if i >= k {
+			break
+		}
+
+		if isRelevant[id] {
+			hits++
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+
+	for i := 0; i < min(k, len(relevant)); i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+
+	return float64(hits) / float64(k), float64(hits) / float64(len(relevant)), dcg / idcg
+}
+
+// roundMetric keeps four decimals, so floating point noise doesn't show up when diffing reports
+func roundMetric(value float64) float64 {
+	return math.Round(value*1e4) / 1e4
+}
@@ -0,0 +1,7 @@
+package services
+
+import "backend-challenge/internal/domain/entities"
+
+type EventService interface {
+	RecordEvents(events []*entities.Event) (int, error)
+}
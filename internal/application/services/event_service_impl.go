@@ -0,0 +1,145 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"log"
+	"slices"
+	"time"
+)
+
+type eventService struct {
+	repo        repositories.EventRepository
+	products    repositories.ProductRepository
+	vectors     *VectorStore
+	experiments *ExperimentService
+}
+
+func NewEventService(repo repositories.EventRepository, products repositories.ProductRepository, vectors *VectorStore, experiments *ExperimentService) EventService {
+	return &eventService{repo: repo, products: products, vectors: vectors, experiments: experiments}
+}
+
+// counters is how much an event batch adds to the counters of one product
+type counters struct {
+	clicks, sold int
+}
+
+// RecordEvents stores a batch of events and adds them to the click and sold counts of their products,
+// returning how many were recorded. Events already stored under the same client event ID, e.g. by a retry
+// of the batch, are skipped, and so are repeated ones within the batch.
+// Clicks count towards ClickCount and purchases towards SoldCount, one per unit bought.
+// Events of sessions and users in the running experiment are stamped with their arm.
+// Batches with events of unknown products are rejected as a whole with a ProductsNotFoundError.
+func (s *eventService) RecordEvents(events []*entities.Event) (int, error) {
+	if err := s.checkProducts(events); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(events))
+	batch := make([]*entities.Event, 0, len(events))
+
+	for _, event := range events {
+		if event.EventID != "" {
+			if seen[event.EventID] {
+				continue
+			}
+
+			seen[event.EventID] = true
+		}
+
+		event.ReceivedAt = now
+
+		if event.Timestamp.IsZero() {
+			event.Timestamp = now
+		}
+
+		if event.Type == entities.EventPurchase && event.Quantity == 0 {
+			event.Quantity = 1
+		}
+
+		batch = append(batch, event)
+	}
+
+	s.experiments.Stamp(batch)
+
+	recorded, err := s.repo.InsertMany(batch)
+
+	if err != nil {
+		return 0, err
+	}
+
+	increments := make(map[string]*counters)
+	// Products are incremented in the order they first appear in the batch
+	order := []string{}
+
+	for _, event := range recorded {
+		if _, ok := increments[event.ProductID]; !ok {
+			increments[event.ProductID] = &counters{}
+			order = append(order, event.ProductID)
+		}
+
+		switch event.Type {
+		case entities.EventClick:
+			increments[event.ProductID].clicks++
+		case entities.EventPurchase:
+			increments[event.ProductID].sold += event.Quantity
+		}
+	}
+
+	for _, productID := range order {
+		increment := increments[productID]
+
+		if increment.clicks == 0 && increment.sold == 0 {
+			continue
+		}
+
+		if err := s.products.IncrementCounters(productID, increment.clicks, increment.sold); err != nil {
+			return 0, err
+		}
+
+		// The counters are already stored, a stale popularity feature is not worth failing the request
+		if err := s.vectors.AddCounts(productID, increment.clicks, increment.sold); err != nil {
+			log.Printf("Error refreshing product vector %s: %v", productID, err)
+		}
+	}
+
+	return len(recorded), nil
+}
+
+// checkProducts fails with a ProductsNotFoundError when events refer to products that don't exist
+func (s *eventService) checkProducts(events []*entities.Event) error {
+	ids := []string{}
+
+	for _, event := range events {
+		if !slices.Contains(ids, event.ProductID) {
+			ids = append(ids, event.ProductID)
+		}
+	}
+
+	products, err := s.products.GetByIDs(ids, repositories.ProductQuery{IncludeUnpublished: true})
+
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool, len(products))
+
+	for _, product := range products {
+		found[product.ID.Hex()] = true
+	}
+
+	missing := []string{}
+
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &ProductsNotFoundError{IDs: missing}
+	}
+
+	return nil
+}
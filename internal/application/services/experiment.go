@@ -0,0 +1,287 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ExperimentArm is a recommender configuration served to a share of the sessions and users
+type ExperimentArm struct {
+	Name      string  `json:"name"`
+	Weight    float64 `json:"weight"`    // share of the traffic, relative to the other arms
+	Strategy  string  `json:"strategy"`  // recommendation strategy, the request's when empty
+	Diversify bool    `json:"diversify"` // re-rank for variety, with Lambda when set
+	Lambda    float64 `json:"lambda"`
+}
+
+// ExperimentConfig defines the running experiment; without arms no experiment runs
+type ExperimentConfig struct {
+	Name string          `json:"name"`
+	Arms []ExperimentArm `json:"arms"`
+}
+
+func DefaultExperimentConfig() ExperimentConfig {
+	return ExperimentConfig{}
+}
+
+// Validate checks that the experiment's arms can be served
+func (c ExperimentConfig) Validate() error {
+	if len(c.Arms) == 0 {
+		return nil
+	}
+
+	if c.Name == "" {
+		return fmt.Errorf("experiment name is required")
+	}
+
+	names := make(map[string]bool, len(c.Arms))
+
+	for _, arm := range c.Arms {
+		if arm.Name == "" || names[arm.Name] {
+			return fmt.Errorf("experiment arms need unique names")
+		}
+
+		names[arm.Name] = true
+
+		if arm.Weight <= 0 {
+			return fmt.Errorf("experiment arm %s needs a positive weight", arm.Name)
+		}
+
+		if arm.Strategy != "" && !slices.Contains(Strategies, arm.Strategy) {
+			return &UnknownStrategyError{Strategy: arm.Strategy}
+		}
+
+		if arm.Lambda < 0 || arm.Lambda > 1 {
+			return fmt.Errorf("experiment arm %s lambda must be between 0 and 1", arm.Name)
+		}
+	}
+
+	return nil
+}
+
+// ExperimentAssignment is the experiment arm a request was bucketed into
+type ExperimentAssignment struct {
+	Experiment string `json:"name"`
+	Arm        string `json:"arm"`
+}
+
+// ArmReport is the click-through of the recommendations served by an experiment arm
+type ArmReport struct {
+	Arm         string  `json:"arm"`
+	Impressions int     `json:"impressions"` // recommended products served
+	Clicks      int     `json:"clicks"`      // served products later clicked by the same session or user
+	CTR         float64 `json:"ctr"`
+}
+
+// ExperimentReport compares the arms of an experiment
+type ExperimentReport struct {
+	Experiment string       `json:"name"`
+	Arms       []*ArmReport `json:"arms"`
+}
+
+// ExperimentService buckets sessions and users into the arms of the running experiment,
+// logs the recommendations served to them and reports the click-through of each arm
+type ExperimentService struct {
+	config      ExperimentConfig
+	totalWeight float64
+	impressions repositories.ImpressionRepository
+	events      repositories.EventRepository
+}
+
+// NewExperimentService expects a validated config
+func NewExperimentService(config ExperimentConfig, impressions repositories.ImpressionRepository, events repositories.EventRepository) *ExperimentService {
+	var totalWeight float64
+
+	for _, arm := range config.Arms {
+		totalWeight += arm.Weight
+	}
+
+	return &ExperimentService{config: config, totalWeight: totalWeight, impressions: impressions, events: events}
+}
+
+// ExperimentUnit is the ID requests and events are bucketed by: the user when known, so they keep their arm
+// across sessions, otherwise the session
+func ExperimentUnit(sessionID, userID string) string {
+	if userID != "" {
+		return "user:" + userID
+	}
+
+	if sessionID != "" {
+		return "session:" + sessionID
+	}
+
+	return ""
+}
+
+// Assign returns the arm a unit is bucketed into. The same unit always lands in the same arm,
+// as long as the experiment's name and arms don't change.
+func (e *ExperimentService) Assign(unit string) (*ExperimentArm, bool) {
+	if unit == "" || e.totalWeight == 0 {
+		return nil, false
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(e.config.Name + ":" + unit))
+
+	// A point in [0, totalWeight) falls in one arm's share
+	point := float64(hash.Sum64()%10000) / 10000 * e.totalWeight
+
+	for i := range e.config.Arms {
+		point -= e.config.Arms[i].Weight
+
+		if point < 0 {
+			return &e.config.Arms[i], true
+		}
+	}
+
+	return &e.config.Arms[len(e.config.Arms)-1], true
+}
+
+// Apply buckets a unit and overrides the recommendation options with its arm's configuration.
+// Units outside the experiment keep the options and get no assignment.
+func (e *ExperimentService) Apply(unit string, opts RecommendationOptions) (RecommendationOptions, *ExperimentAssignment) {
+	arm, ok := e.Assign(unit)
+
+	if !ok {
+		return opts, nil
+	}
+
+	if arm.Strategy != "" {
+		opts.Strategy = arm.Strategy
+	}
+
+	if arm.Diversify {
+		opts.Diversify = true
+
+		if arm.Lambda > 0 {
+			opts.Lambda = arm.Lambda
+		}
+	}
+
+	return opts, &ExperimentAssignment{Experiment: e.config.Name, Arm: arm.Name}
+}
+
+// Stamp marks the events of bucketed sessions and users with their experiment arm.
+// Arms sent by clients are never trusted.
+func (e *ExperimentService) Stamp(events []*entities.Event) {
+	for _, event := range events {
+		event.Experiment, event.Arm = "", ""
+
+		if arm, ok := e.Assign(ExperimentUnit(event.SessionID, event.UserID)); ok {
+			event.Experiment = e.config.Name
+			event.Arm = arm.Name
+		}
+	}
+}
+
+// RecordImpression logs the recommendations served to a bucketed unit. Failing to log them
+// only skews the report, so errors are logged instead of failing the request.
+func (e *ExperimentService) RecordImpression(unit string, assignment *ExperimentAssignment, productID string, result *RecommendationResult) {
+	if assignment == nil || len(result.Recommendations) == 0 {
+		return
+	}
+
+	recommended := make([]string, 0, len(result.Recommendations))
+
+	for _, recommendation := range result.Recommendations {
+		recommended = append(recommended, recommendation.Product.ID.Hex())
+	}
+
+	err := e.impressions.Insert(&entities.Impression{
+		Experiment:  assignment.Experiment,
+		Arm:         assignment.Arm,
+		Unit:        unit,
+		ProductID:   productID,
+		Recommended: recommended,
+		Timestamp:   time.Now(),
+	})
+
+	if err != nil {
+		log.Printf("Error recording impression of experiment %s: %v", assignment.Experiment, err)
+	}
+}
+
+// Report computes the click-through of every arm of an experiment, the running one when name is empty.
+// A click counts when its session or user was served the clicked product by that arm before,
+// and each served product counts at most once per session or user.
+func (e *ExperimentService) Report(name string) (*ExperimentReport, error) {
+	if name == "" {
+		name = e.config.Name
+	}
+
+	impressions, err := e.impressions.GetByExperiment(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clicks, err := e.events.GetByExperiment(name, []entities.EventType{entities.EventClick})
+
+	if err != nil {
+		return nil, err
+	}
+
+	type served struct {
+		arm, unit, productID string
+	}
+
+	// firstServed is when each product was first served to each unit by each arm
+	firstServed := make(map[served]time.Time)
+	arms := make(map[string]*ArmReport)
+
+	for _, arm := range e.config.Arms {
+		if name == e.config.Name {
+			arms[arm.Name] = &ArmReport{Arm: arm.Name}
+		}
+	}
+
+	for _, impression := range impressions {
+		if arms[impression.Arm] == nil {
+			arms[impression.Arm] = &ArmReport{Arm: impression.Arm}
+		}
+
+		for _, productID := range impression.Recommended {
+			key := served{impression.Arm, impression.Unit, productID}
+
+			if at, ok := firstServed[key]; !ok || impression.Timestamp.Before(at) {
+				if !ok {
+					arms[impression.Arm].Impressions++
+				}
+				firstServed[key] = impression.Timestamp
+			}
+		}
+	}
+
+	clicked := make(map[served]bool)
+
+	for _, click := range clicks {
+		key := served{click.Arm, ExperimentUnit(click.SessionID, click.UserID), click.ProductID}
+
+		if at, ok := firstServed[key]; ok && !click.Timestamp.Before(at) && !clicked[key] {
+			clicked[key] = true
+			arms[click.Arm].Clicks++
+		}
+	}
+
+	report := &ExperimentReport{Experiment: name, Arms: make([]*ArmReport, 0, len(arms))}
+
+	for _, arm := range arms {
+		if arm.Impressions > 0 {
+			arm.CTR = float64(arm.Clicks) / float64(arm.Impressions)
+		}
+
+		report.Arms = append(report.Arms, arm)
+	}
+
+	sort.Slice(report.Arms, func(i, j int) bool {
+		return report.Arms[i].Arm < report.Arms[j].Arm
+	})
+
+	return report, nil
+}
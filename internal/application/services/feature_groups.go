@@ -0,0 +1,307 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Feature groups, each one compared on its own and combined with a configurable weight
+const (
+	GroupCategory     = "category"
+	GroupName         = "name"
+	GroupDescription  = "description"
+	GroupPrice        = "price"
+	GroupPopularity   = "popularity"
+	GroupVariant      = "variant"
+	GroupAvailability = "availability"
+)
+
+const (
+	inStockFeature    = "availability_in_stock"
+	outOfStockFeature = "availability_out_of_stock"
+)
+
+const (
+	// priceBandsPerDoubling is how many price bands a twofold price difference spans
+	priceBandsPerDoubling = 2
+	// maxPriceBand caps how far from the category median a band can be, in both directions
+	maxPriceBand = 6
+)
+
+// anchorGroups tell what a product is. The other groups, like price bands and popularity, are found in
+// almost every vector, so two products sharing only those have nothing in common.
+var anchorGroups = []string{GroupCategory, GroupName, GroupDescription}
+
+// maxExplainedFeatures is how many contributing features an explanation lists
+const maxExplainedFeatures = 10
+
+// defaultGroupWeights make sharing a category matter more than sharing words
+func defaultGroupWeights() map[string]float64 {
+	return map[string]float64{
+		GroupCategory:     3.0,
+		GroupName:         2.0,
+		GroupDescription:  1.0,
+		GroupPrice:        1.0,
+		GroupPopularity:   0.5,
+		GroupVariant:      0.5,
+		GroupAvailability: 0.25,
+	}
+}
+
+// featureGroup splits a feature key into the group it belongs to and its name within the group
+func featureGroup(key string) (group, feature string) {
+	switch {
+	case strings.HasPrefix(key, "category_"):
+		return GroupCategory, strings.TrimPrefix(key, "category_")
+	case strings.HasPrefix(key, "name_"):
+		return GroupName, strings.TrimPrefix(key, "name_")
+	case strings.HasPrefix(key, "description_"):
+		return GroupDescription, strings.TrimPrefix(key, "description_")
+	case strings.HasPrefix(key, "price_"):
+		return GroupPrice, strings.TrimPrefix(key, "price_")
+	case key == "click_count" || key == "sold_count":
+		return GroupPopularity, key
+	case strings.HasPrefix(key, "variant_"):
+		return GroupVariant, strings.TrimPrefix(key, "variant_")
+	case strings.HasPrefix(key, "availability_"):
+		return GroupAvailability, strings.TrimPrefix(key, "availability_")
+	default:
+		return "other", key
+	}
+}
+
+// priceBandFeatures places a price in a log scaled band around the reference price, so products priced
+// alike relative to their category share a band. Neighboring bands get partial credit.
+// Without a reference price, the band is taken from the absolute price.
+func priceBandFeatures(price, reference float64) map[string]float64 {
+	if price <= 0 {
+		return nil
+	}
+
+	if reference <= 0 {
+		reference = 1
+	}
+
+	band := int(math.Round(math.Log2(price/reference) * priceBandsPerDoubling))
+
+	if reference != 1 {
+		band = max(-maxPriceBand, min(maxPriceBand, band))
+	}
+
+	return map[string]float64{
+		fmt.Sprintf("price_band_%d", band):   1.0,
+		fmt.Sprintf("price_band_%d", band-1): 0.5,
+		fmt.Sprintf("price_band_%d", band+1): 0.5,
+	}
+}
+
+// variantOptions splits a variant value such as "Negro / XL" into its normalized options
+func variantOptions(value string) []string {
+	options := []string{}
+
+	for _, option := range strings.FieldsFunc(NormalizeText(value), func(r rune) bool {
+		return r == '/' || r == ','
+	}) {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+
+	return options
+}
+
+// rankedGroups hold percentile ranks rather than feature weights. Cosine would cancel their magnitude out,
+// so a product ranked 0.1 would match one ranked 0.9, and they are compared by rank distance instead.
+var rankedGroups = map[string]bool{GroupPopularity: true}
+
+// groupStats holds the per group dot products and squared magnitudes of two vectors.
+// Ranked groups hold instead the closeness of each rank of the target to the other vector's, and how many ranks the target has.
+type groupStats struct {
+	dot, magnitudeA, magnitudeB map[string]float64
+	closeness                   map[string]float64
+	ranks                       map[string]int
+}
+
+func newGroupStats(vec1, vec2 map[string]float64) groupStats {
+	stats := groupStats{
+		dot:        make(map[string]float64),
+		magnitudeA: make(map[string]float64),
+		magnitudeB: make(map[string]float64),
+		closeness:  make(map[string]float64),
+		ranks:      make(map[string]int),
+	}
+
+	for key, valueA := range vec1 {
+		group, _ := featureGroup(key)
+
+		if rankedGroups[group] {
+			stats.ranks[group]++
+
+			if valueB, ok := vec2[key]; ok {
+				stats.closeness[group] += rankCloseness(valueA, valueB)
+			}
+
+			continue
+		}
+
+		stats.magnitudeA[group] += valueA * valueA
+
+		if valueB, ok := vec2[key]; ok {
+			stats.dot[group] += valueA * valueB
+		}
+	}
+
+	for key, valueB := range vec2 {
+		group, _ := featureGroup(key)
+
+		if !rankedGroups[group] {
+			stats.magnitudeB[group] += valueB * valueB
+		}
+	}
+
+	return stats
+}
+
+// rankCloseness is 1 for equal percentile ranks and 0 for the least and most popular products
+func rankCloseness(rankA, rankB float64) float64 {
+	return 1 - math.Abs(rankA-rankB)
+}
+
+// groupSimilarity scores a group from 0 to 1: the mean closeness of the ranks for ranked groups, the cosine otherwise
+func (stats groupStats) groupSimilarity(group string) float64 {
+	if rankedGroups[group] {
+		if stats.ranks[group] == 0 {
+			return 0
+		}
+		return stats.closeness[group] / float64(stats.ranks[group])
+	}
+
+	if stats.dot[group] == 0 || stats.magnitudeA[group] == 0 || stats.magnitudeB[group] == 0 {
+		return 0
+	}
+
+	return stats.dot[group] / (math.Sqrt(stats.magnitudeA[group]) * math.Sqrt(stats.magnitudeB[group]))
+}
+
+// anchored tells whether the vectors share a feature of any anchor group
+func (stats groupStats) anchored() bool {
+	for _, group := range anchorGroups {
+		if stats.dot[group] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// totalWeight is the sum of the weights of the groups the target vector has features in.
+// Groups the target lacks say nothing about similarity, so they don't dilute the score.
+func (s *RecommendationService) totalWeight(stats groupStats) float64 {
+	var total float64
+
+	for group, magnitude := range stats.magnitudeA {
+		if magnitude > 0 {
+			total += s.weights[group]
+		}
+	}
+
+	for group, ranks := range stats.ranks {
+		if ranks > 0 {
+			total += s.weights[group]
+		}
+	}
+
+	return total
+}
+
+// Similarity computes the cosine similarity of each feature group, or the rank closeness of ranked groups,
+// and combines them with the configured weights.
+// The first vector is the target; the result is in the same 0 to 1 range as CosineSimilarity.
+// Vectors sharing no category or text term score zero, whatever else they share.
+func (s *RecommendationService) Similarity(vec1, vec2 map[string]float64) float64 {
+	stats := newGroupStats(vec1, vec2)
+
+	total := s.totalWeight(stats)
+
+	if total == 0 {
+		return 0 // Avoid division by zero
+	}
+
+	if !stats.anchored() {
+		return 0
+	}
+
+	var score float64
+
+	for group := range stats.dot {
+		score += s.weights[group] * stats.groupSimilarity(group)
+	}
+
+	for group := range stats.ranks {
+		score += s.weights[group] * stats.groupSimilarity(group)
+	}
+
+	return score / total
+}
+
+// Explain breaks a similarity score down into the features both vectors share.
+// Contributions are each feature's weighted term of its group's cosine, or of its group's mean rank closeness,
+// so they add up to the score.
+func (s *RecommendationService) Explain(vec1, vec2 map[string]float64) []FeatureContribution {
+	stats := newGroupStats(vec1, vec2)
+
+	contributions := []FeatureContribution{}
+
+	total := s.totalWeight(stats)
+
+	if total == 0 {
+		return contributions
+	}
+
+	if !stats.anchored() {
+		return contributions
+	}
+
+	for key, valueA := range vec1 {
+		valueB, ok := vec2[key]
+		group, feature := featureGroup(key)
+
+		if ok && rankedGroups[group] {
+			if closeness := rankCloseness(valueA, valueB); closeness > 0 {
+				contributions = append(contributions, FeatureContribution{
+					Feature:      feature,
+					Group:        group,
+					Contribution: s.weights[group] * closeness / float64(stats.ranks[group]) / total,
+				})
+			}
+			continue
+		}
+
+		if !ok || valueA*valueB == 0 {
+			continue
+		}
+
+		norm := math.Sqrt(stats.magnitudeA[group]) * math.Sqrt(stats.magnitudeB[group])
+
+		contributions = append(contributions, FeatureContribution{
+			Feature:      feature,
+			Group:        group,
+			Contribution: s.weights[group] * valueA * valueB / norm / total,
+		})
+	}
+
+	sort.Slice(contributions, func(i, j int) bool {
+		if contributions[i].Contribution != contributions[j].Contribution {
+			return contributions[i].Contribution > contributions[j].Contribution
+		}
+		return contributions[i].Feature < contributions[j].Feature
+	})
+
+	if len(contributions) > maxExplainedFeatures {
+		contributions = contributions[:maxExplainedFeatures]
+	}
+
+	return contributions
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+)
+
+// HNSW is a Hierarchical Navigable Small World graph (Malkov and Yashunin) answering approximate
+// nearest neighbor queries over unit length vectors, by cosine similarity.
+// Removed vectors stay in the graph to keep it navigable but are never returned, until they make up
+// hnswMaxRemovedRatio of the live vectors and the graph is rebuilt without them.
+type HNSW struct {
+	mu sync.RWMutex
+
+	m              int // neighbors linked per node above the bottom layer
+	maxNeighbors0  int // neighbors linked per node in the bottom layer
+	efConstruction int
+	levelFactor    float64
+	rng            *rand.Rand
+
+	nodes    []*hnswNode
+	byID     map[string]int
+	entry    int // node searches start from, -1 while empty
+	maxLevel int
+}
+
+// hnswMaxRemovedRatio is how many removed nodes, relative to the live ones, the graph keeps before being rebuilt.
+// Searches look further by one node per removed one, so they would otherwise slow down with every update.
+const hnswMaxRemovedRatio = 0.25
+
+type hnswNode struct {
+	id        string
+	vector    []float64
+	neighbors [][]int // per layer, from the bottom one
+	removed   bool
+}
+
+func NewHNSW(m, efConstruction int) *HNSW {
+	m = max(m, 2)
+
+	return &HNSW{
+		m:              m,
+		maxNeighbors0:  2 * m,
+		efConstruction: max(efConstruction, m),
+		levelFactor:    1 / math.Log(float64(m)),
+		rng:            rand.New(rand.NewSource(embeddingSeed)),
+		byID:           make(map[string]int),
+		entry:          -1,
+	}
+}
+
+// Len returns how many vectors can be returned
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.byID)
+}
+
+// Add inserts a vector, replacing the previous one of the same ID. Adding the same vector again does nothing.
+func (h *HNSW) Add(id string, vector []float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if index, ok := h.byID[id]; ok && slices.Equal(h.nodes[index].vector, vector) {
+		return
+	}
+
+	h.removeLocked(id)
+	h.insertLocked(id, vector)
+}
+
+// insertLocked links a new node for the vector into the graph
+func (h *HNSW) insertLocked(id string, vector []float64) {
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelFactor)
+
+	node := &hnswNode{id: id, vector: vector, neighbors: make([][]int, level+1)}
+	index := len(h.nodes)
+	h.nodes = append(h.nodes, node)
+	h.byID[id] = index
+
+	if h.entry < 0 {
+		h.entry = index
+		h.maxLevel = level
+		return
+	}
+
+	// Descend greedily through the layers above the new node's
+	entry := h.entry
+
+	for layer := h.maxLevel; layer > level; layer-- {
+		entry = h.searchLayer(vector, []int{entry}, 1, layer)[0].node
+	}
+
+	entries := []int{entry}
+
+	for layer := min(level, h.maxLevel); layer >= 0; layer-- {
+		found := h.searchLayer(vector, entries, h.efConstruction, layer)
+
+		neighbors := make([]int, 0, h.m)
+
+		for i := 0; i < len(found) && i < h.m; i++ {
+			neighbors = append(neighbors, found[i].node)
+		}
+
+		node.neighbors[layer] = neighbors
+
+		for _, neighbor := range neighbors {
+			h.link(neighbor, index, layer)
+		}
+
+		entries = entries[:0]
+
+		for _, candidate := range found {
+			entries = append(entries, candidate.node)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.entry = index
+		h.maxLevel = level
+	}
+}
+
+// Remove stops returning a vector
+func (h *HNSW) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(id)
+}
+
+func (h *HNSW) removeLocked(id string) {
+	index, ok := h.byID[id]
+
+	if !ok {
+		return
+	}
+
+	h.nodes[index].removed = true
+	delete(h.byID, id)
+
+	if removed := len(h.nodes) - len(h.byID); float64(removed) > hnswMaxRemovedRatio*float64(len(h.byID)) {
+		h.compactLocked()
+	}
+}
+
+// compactLocked rebuilds the graph from its live nodes, in the order they were added
+func (h *HNSW) compactLocked() {
+	nodes := h.nodes
+
+	h.nodes = make([]*hnswNode, 0, len(h.byID))
+	h.byID = make(map[string]int, len(h.byID))
+	h.entry = -1
+	h.maxLevel = 0
+
+	for _, node := range nodes {
+		if !node.removed {
+			h.insertLocked(node.id, node.vector)
+		}
+	}
+}
+
+// Search returns up to k of the vectors most similar to the query, best first, considering ef candidates
+func (h *HNSW) Search(vector []float64, k, ef int) []ScoredCandidate {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 || k <= 0 {
+		return []ScoredCandidate{}
+	}
+
+	entry := h.entry
+
+	for layer := h.maxLevel; layer > 0; layer-- {
+		entry = h.searchLayer(vector, []int{entry}, 1, layer)[0].node
+	}
+
+	// Removed nodes are skipped from the results, so look a bit further
+	found := h.searchLayer(vector, []int{entry}, max(ef, k)+len(h.nodes)-len(h.byID), 0)
+
+	results := make([]ScoredCandidate, 0, k)
+
+	for _, candidate := range found {
+		node := h.nodes[candidate.node]
+
+		if node.removed {
+			continue
+		}
+
+		results = append(results, ScoredCandidate{ID: node.id, Score: 1 - candidate.distance})
+
+		if len(results) == k {
+			break
+		}
+	}
+
+	return results
+}
+
+// link adds target to the neighbors of node in a layer, dropping the farthest one when there are too many
+func (h *HNSW) link(node, target, layer int) {
+	neighbors := append(h.nodes[node].neighbors[layer], target)
+
+	limit := h.m
+
+	if layer == 0 {
+		limit = h.maxNeighbors0
+	}
+
+	if len(neighbors) > limit {
+		vector := h.nodes[node].vector
+		farthest := 0
+
+		for i := range neighbors {
+			if h.distance(vector, neighbors[i]) > h.distance(vector, neighbors[farthest]) {
+				farthest = i
+			}
+		}
+
+		neighbors = append(neighbors[:farthest], neighbors[farthest+1:]...)
+	}
+
+	h.nodes[node].neighbors[layer] = neighbors
+}
+
+// searchLayer is a best first search of a layer from the entry nodes, returning the ef closest nodes found, closest first
+func (h *HNSW) searchLayer(vector []float64, entries []int, ef, layer int) []hnswCandidate {
+	visited := make(map[int]bool, ef*4)
+	candidates := &hnswQueue{}            // closest first, still to expand
+	results := &hnswQueue{farthest: true} // farthest first, the best ef found
+
+	for _, entry := range entries {
+		visited[entry] = true
+		candidate := hnswCandidate{node: entry, distance: h.distance(vector, entry)}
+		heap.Push(candidates, candidate)
+		heap.Push(results, candidate)
+	}
+
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(hnswCandidate)
+
+		if current.distance > results.items[0].distance && results.Len() >= ef {
+			break
+		}
+
+		if layer >= len(h.nodes[current.node].neighbors) {
+			continue
+		}
+
+		for _, neighbor := range h.nodes[current.node].neighbors[layer] {
+			if visited[neighbor] {
+				continue
+			}
+
+			visited[neighbor] = true
+			distance := h.distance(vector, neighbor)
+
+			if results.Len() < ef || distance < results.items[0].distance {
+				heap.Push(candidates, hnswCandidate{node: neighbor, distance: distance})
+				heap.Push(results, hnswCandidate{node: neighbor, distance: distance})
+
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := make([]hnswCandidate, results.Len())
+
+	for i := len(found) - 1; i >= 0; i-- {
+		found[i] = heap.Pop(results).(hnswCandidate)
+	}
+
+	return found
+}
+
+// distance is the cosine distance between a query and a node, both of unit length
+func (h *HNSW) distance(vector []float64, node int) float64 {
+	var dot float64
+
+	for i, value := range h.nodes[node].vector {
+		dot += value * vector[i]
+	}
+
+	return 1 - dot
+}
+
+type hnswCandidate struct {
+	node     int
+	distance float64
+}
+
+// hnswQueue is a heap of candidates, closest first unless farthest is set
+type hnswQueue struct {
+	items    []hnswCandidate
+	farthest bool
+}
+
+func (q *hnswQueue) Len() int { return len(q.items) }
+
+func (q *hnswQueue) Less(i, j int) bool {
+	if q.farthest {
+		return q.items[i].distance > q.items[j].distance
+	}
+	return q.items[i].distance < q.items[j].distance
+}
+
+func (q *hnswQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *hnswQueue) Push(x any) { q.items = append(q.items, x.(hnswCandidate)) }
+
+func (q *hnswQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/repositories"
+	"sort"
+)
+
+// GroupBehavior is the explanation group of the behavioral signals blended by the hybrid strategy
+const GroupBehavior = "behavior"
+
+// HybridConfig weighs the signals blended by the hybrid strategy
+type HybridConfig struct {
+	Content    float64 `json:"content"`    // grouped cosine similarity of the products
+	CoPurchase float64 `json:"coPurchase"` // how often the products are bought together, relative to the strongest pair
+	Popularity float64 `json:"popularity"` // recent interactions, relative to the most popular product
+}
+
+func DefaultHybridConfig() HybridConfig {
+	return HybridConfig{
+		Content:    0.6,
+		CoPurchase: 0.3,
+		Popularity: 0.1,
+	}
+}
+
+// hybridRecommender linearly blends content similarity, co-purchase strength and time decayed popularity.
+// Candidates are the products related by content or bought together; popularity only reorders them.
+type hybridRecommender struct {
+	vectors      *VectorStore
+	scorer       *RecommendationService
+	similarities repositories.ItemSimilarityRepository
+	popularity   *PopularityService
+	weights      HybridConfig
+}
+
+func NewHybridRecommender(vectors *VectorStore, scorer *RecommendationService, similarities repositories.ItemSimilarityRepository, popularity *PopularityService, config HybridConfig) Recommender {
+	return &hybridRecommender{
+		vectors:      vectors,
+		scorer:       scorer,
+		similarities: similarities,
+		popularity:   popularity,
+		weights:      config,
+	}
+}
+
+// hybridSignals are the normalized signals of a candidate, before weighting
+type hybridSignals struct {
+	content, coPurchase, popularity float64
+}
+
+func (r *hybridRecommender) Recommend(productID string, opts RecommendationOptions) ([]ScoredCandidate, error) {
+	targetVector, ok := r.vectors.Get(productID)
+
+	if !ok {
+		return []ScoredCandidate{}, nil
+	}
+
+	coPurchases, err := r.coPurchases(productID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := r.vectors.Candidates(productID)
+
+	for id := range coPurchases {
+		if id != productID {
+			ids = append(ids, id)
+		}
+	}
+
+	// Products bought together that no longer exist have no vector and are skipped
+	candidates := r.vectors.Vectors(r.vectors.Visible(ids, opts))
+
+	scored := make([]ScoredCandidate, 0, len(candidates))
+
+	for id, vector := range candidates {
+		signals, err := r.signals(targetVector, vector, id, coPurchases)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if signals.content <= 0 && signals.coPurchase <= 0 {
+			continue
+		}
+
+		score := applyStockPenalty(r.blend(signals), vector, opts)
+
+		if score <= 0 || score < opts.MinScore {
+			continue
+		}
+
+		scored = append(scored, ScoredCandidate{ID: id, Score: score})
+	}
+
+	sortCandidates(scored)
+
+	return paginate(r.scorer.Diversify(scored, candidates, opts), opts), nil
+}
+
+// Explain breaks the blended scores down into the content features, scaled by the content weight, and the behavioral signals.
+// The products bought together with the target are looked up once for every candidate.
+func (r *hybridRecommender) Explain(productID string, candidateIDs []string) map[string][]FeatureContribution {
+	explanations := make(map[string][]FeatureContribution, len(candidateIDs))
+
+	coPurchases, err := r.coPurchases(productID)
+
+	if err != nil {
+		return explanations
+	}
+
+	vectors := r.vectors.Vectors(append([]string{productID}, candidateIDs...))
+
+	for _, id := range candidateIDs {
+		explanations[id] = r.explain(vectors[productID], vectors[id], id, coPurchases)
+	}
+
+	return explanations
+}
+
+// explain breaks down the blended score of a single candidate
+func (r *hybridRecommender) explain(targetVector, vector map[string]float64, id string, coPurchases map[string]float64) []FeatureContribution {
+	contributions := []FeatureContribution{}
+
+	signals, err := r.signals(targetVector, vector, id, coPurchases)
+
+	if err != nil {
+		return contributions
+	}
+
+	total := r.totalWeight()
+
+	if total == 0 {
+		return contributions
+	}
+
+	for _, contribution := range r.scorer.Explain(targetVector, vector) {
+		contribution.Contribution *= r.weights.Content / total
+		contributions = append(contributions, contribution)
+	}
+
+	for feature, value := range map[string]float64{"co_purchase": r.weights.CoPurchase * signals.coPurchase, "popularity": r.weights.Popularity * signals.popularity} {
+		if value > 0 {
+			contributions = append(contributions, FeatureContribution{Feature: feature, Group: GroupBehavior, Contribution: value / total})
+		}
+	}
+
+	sort.Slice(contributions, func(i, j int) bool {
+		if contributions[i].Contribution != contributions[j].Contribution {
+			return contributions[i].Contribution > contributions[j].Contribution
+		}
+		return contributions[i].Feature < contributions[j].Feature
+	})
+
+	if len(contributions) > maxExplainedFeatures {
+		contributions = contributions[:maxExplainedFeatures]
+	}
+
+	return contributions
+}
+
+func (r *hybridRecommender) signals(targetVector, vector map[string]float64, id string, coPurchases map[string]float64) (hybridSignals, error) {
+	popularity, err := r.popularity.Normalized(id)
+
+	if err != nil {
+		return hybridSignals{}, err
+	}
+
+	return hybridSignals{
+		content:    r.scorer.Similarity(targetVector, vector),
+		coPurchase: coPurchases[id],
+		popularity: popularity,
+	}, nil
+}
+
+func (r *hybridRecommender) blend(signals hybridSignals) float64 {
+	total := r.totalWeight()
+
+	if total == 0 {
+		return 0
+	}
+
+	return (r.weights.Content*signals.content + r.weights.CoPurchase*signals.coPurchase + r.weights.Popularity*signals.popularity) / total
+}
+
+func (r *hybridRecommender) totalWeight() float64 {
+	return r.weights.Content + r.weights.CoPurchase + r.weights.Popularity
+}
+
+// coPurchases returns the products bought together with a product, scored relative to the strongest one
+func (r *hybridRecommender) coPurchases(productID string) (map[string]float64, error) {
+	similarity, err := r.similarities.GetByProductID(productID)
+
+	if err != nil || similarity == nil || len(similarity.Neighbors) == 0 {
+		return map[string]float64{}, err
+	}
+
+	// Neighbors are sorted, so the first one is the strongest
+	strongest := similarity.Neighbors[0].Score
+
+	coPurchases := make(map[string]float64, len(similarity.Neighbors))
+
+	for _, neighbor := range similarity.Neighbors {
+		if strongest > 0 {
+			coPurchases[neighbor.ProductID] = neighbor.Score / strongest
+		}
+	}
+
+	return coPurchases, nil
+}
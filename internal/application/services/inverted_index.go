@@ -0,0 +1,118 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"strings"
+	"sync"
+)
+
+// InvertedIndex maps category and text token features to the products that contain them,
+// so only products sharing at least one of them with the target need to be scored.
+type InvertedIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]struct{} // feature key -> product IDs
+	keys     map[string][]string            // product ID -> indexed feature keys
+}
+
+func NewInvertedIndex() *InvertedIndex {
+	return &InvertedIndex{
+		postings: make(map[string]map[string]struct{}),
+		keys:     make(map[string][]string),
+	}
+}
+
+// Add indexes a product vector, replacing any previous entry for the same product
+func (i *InvertedIndex) Add(productID string, vector *entities.ProductVector) {
+	keys := indexKeys(vector)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.removeLocked(productID)
+
+	for _, key := range keys {
+		posting, ok := i.postings[key]
+
+		if !ok {
+			posting = make(map[string]struct{})
+			i.postings[key] = posting
+		}
+
+		posting[productID] = struct{}{}
+	}
+
+	i.keys[productID] = keys
+}
+
+// Remove drops a product from every posting list
+func (i *InvertedIndex) Remove(productID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.removeLocked(productID)
+}
+
+func (i *InvertedIndex) removeLocked(productID string) {
+	for _, key := range i.keys[productID] {
+		delete(i.postings[key], productID)
+
+		if len(i.postings[key]) == 0 {
+			delete(i.postings, key)
+		}
+	}
+
+	delete(i.keys, productID)
+}
+
+// Candidates returns the IDs of the products sharing at least one indexed feature with the vector
+func (i *InvertedIndex) Candidates(vector *entities.ProductVector) []string {
+	return i.Lookup(indexKeys(vector))
+}
+
+// Lookup returns the IDs of the products with at least one of the feature keys
+func (i *InvertedIndex) Lookup(keys []string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	candidates := []string{}
+
+	for _, key := range keys {
+		for id := range i.postings[key] {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+
+			seen[id] = struct{}{}
+			candidates = append(candidates, id)
+		}
+	}
+
+	return candidates
+}
+
+// indexKeys returns the feature keys of a vector worth indexing: categories and text tokens.
+// Popularity features are shared by almost every product and would make every posting list the catalog.
+func indexKeys(vector *entities.ProductVector) []string {
+	unique := make(map[string]struct{})
+
+	for key := range vector.Features {
+		if strings.HasPrefix(key, "category_") {
+			unique[key] = struct{}{}
+		}
+	}
+
+	for _, terms := range vector.Terms {
+		for key := range terms {
+			unique[key] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(unique))
+
+	for key := range unique {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// sparseRow is a row of a sparse matrix: the columns with a value and their values
+type sparseRow struct {
+	columns []int
+	values  []float64
+}
+
+// truncatedSVD approximates the top rank right singular vectors and singular values of a sparse matrix
+// with columns columns, by randomized subspace iteration (Halko, Martinsson and Tropp).
+// It returns V as one row of up to rank values per column, scaled so that a row of the matrix times V
+// is that row's coordinates in the latent space, and the singular values.
+func truncatedSVD(rows []sparseRow, columns, rank, oversampling, iterations int, rng *rand.Rand) ([][]float64, []float64) {
+	width := min(rank+oversampling, len(rows), columns)
+
+	if width == 0 {
+		return make([][]float64, columns), nil
+	}
+
+	// Sample the range of the matrix with a random projection, then refine it with power iterations
+	omega := make([][]float64, columns)
+
+	for i := range omega {
+		omega[i] = make([]float64, width)
+
+		for j := range omega[i] {
+			omega[i][j] = rng.NormFloat64()
+		}
+	}
+
+	q := orthonormalize(multiplySparse(rows, omega, width))
+
+	for i := 0; i < iterations; i++ {
+		z := orthonormalize(multiplySparseTransposed(rows, q, columns, width))
+		q = orthonormalize(multiplySparse(rows, z, width))
+	}
+
+	// B = Qᵀ A is small; its singular values are the matrix's and V follows from the eigenvectors of B Bᵀ
+	bt := multiplySparseTransposed(rows, q, columns, width)
+
+	gram := make([][]float64, width)
+
+	for i := range gram {
+		gram[i] = make([]float64, width)
+	}
+
+	for _, row := range bt {
+		for i := 0; i < width; i++ {
+			if row[i] == 0 {
+				continue
+			}
+
+			for j := i; j < width; j++ {
+				gram[i][j] += row[i] * row[j]
+			}
+		}
+	}
+
+	for i := 0; i < width; i++ {
+		for j := 0; j < i; j++ {
+			gram[i][j] = gram[j][i]
+		}
+	}
+
+	eigenvalues, eigenvectors := symmetricEigen(gram)
+
+	order := make([]int, width)
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.Slice(order, func(i, j int) bool { return eigenvalues[order[i]] > eigenvalues[order[j]] })
+
+	singular := []float64{}
+
+	for _, k := range order {
+		if len(singular) == rank || eigenvalues[k] <= 1e-12 {
+			break
+		}
+
+		singular = append(singular, math.Sqrt(eigenvalues[k]))
+	}
+
+	// V = Bᵀ W Σ⁻¹, so a row times V is U Σ: the row's coordinates scaled by how much each dimension explains
+	v := make([][]float64, columns)
+
+	for c, row := range bt {
+		v[c] = make([]float64, len(singular))
+
+		for d := range singular {
+			k := order[d]
+
+			var value float64
+
+			for i := 0; i < width; i++ {
+				value += row[i] * eigenvectors[i][k]
+			}
+
+			v[c][d] = value / singular[d]
+		}
+	}
+
+	return v, singular
+}
+
+// multiplySparse computes A X for a sparse A and a dense X with width columns
+func multiplySparse(rows []sparseRow, x [][]float64, width int) [][]float64 {
+	result := make([][]float64, len(rows))
+
+	for r, row := range rows {
+		result[r] = make([]float64, width)
+
+		for i, column := range row.columns {
+			value := row.values[i]
+
+			for j := 0; j < width; j++ {
+				result[r][j] += value * x[column][j]
+			}
+		}
+	}
+
+	return result
+}
+
+// multiplySparseTransposed computes Aᵀ Y for a sparse A with columns columns and a dense Y with width columns
+func multiplySparseTransposed(rows []sparseRow, y [][]float64, columns, width int) [][]float64 {
+	result := make([][]float64, columns)
+
+	for c := range result {
+		result[c] = make([]float64, width)
+	}
+
+	for r, row := range rows {
+		for i, column := range row.columns {
+			value := row.values[i]
+
+			for j := 0; j < width; j++ {
+				result[column][j] += value * y[r][j]
+			}
+		}
+	}
+
+	return result
+}
+
+// orthonormalize makes the columns of a dense matrix orthonormal in place, by modified Gram-Schmidt.
+// Columns that are linearly dependent on the previous ones are zeroed.
+func orthonormalize(m [][]float64) [][]float64 {
+	if len(m) == 0 {
+		return m
+	}
+
+	width := len(m[0])
+
+	for j := 0; j < width; j++ {
+		for k := 0; k < j; k++ {
+			var dot float64
+
+			for i := range m {
+				dot += m[i][j] * m[i][k]
+			}
+
+			for i := range m {
+				m[i][j] -= dot * m[i][k]
+			}
+		}
+
+		var norm float64
+
+		for i := range m {
+			norm += m[i][j] * m[i][j]
+		}
+
+		norm = math.Sqrt(norm)
+
+		for i := range m {
+			if norm > 1e-10 {
+				m[i][j] /= norm
+			} else {
+				m[i][j] = 0
+			}
+		}
+	}
+
+	return m
+}
+
+// symmetricEigen computes the eigenvalues and eigenvectors (as columns) of a small symmetric matrix
+// with the cyclic Jacobi method
+func symmetricEigen(m [][]float64) ([]float64, [][]float64) {
+	n := len(m)
+
+	a := make([][]float64, n)
+	vectors := make([][]float64, n)
+
+	for i := range a {
+		a[i] = append([]float64(nil), m[i]...)
+		vectors[i] = make([]float64, n)
+		vectors[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 100; sweep++ {
+		var offDiagonal float64
+
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				offDiagonal += a[i][j] * a[i][j]
+			}
+		}
+
+		if offDiagonal < 1e-22 {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(a[p][q]) < 1e-300 {
+					continue
+				}
+
+				// Rotate rows and columns p and q so that a[p][q] becomes zero
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+
+				for k := 0; k < n; k++ {
+					vkp, vkq := vectors[k][p], vectors[k][q]
+					vectors[k][p] = c*vkp - s*vkq
+					vectors[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	values := make([]float64, n)
+
+	for i := range values {
+		values[i] = a[i][i]
+	}
+
+	return values, vectors
+}
@@ -0,0 +1,24 @@
+package services
+
+import "backend-challenge/internal/domain/entities"
+
+// InvalidMerchandisingRuleError is returned when a rule couldn't do anything as configured
+type InvalidMerchandisingRuleError struct {
+	Reason string
+}
+
+func (e *InvalidMerchandisingRuleError) Error() string {
+	return "invalid merchandising rule: " + e.Reason
+}
+
+type MerchandisingService interface {
+	GetRule(id string) (*entities.MerchandisingRule, error)
+	GetAllRules() ([]*entities.MerchandisingRule, error)
+	CreateRule(rule *entities.MerchandisingRule) error
+	UpdateRule(rule *entities.MerchandisingRule) error
+	DeleteRule(id string) error
+	Load() error
+	Watch(listener func())
+	Rules(product *entities.Product) []*entities.MerchandisingRule
+	Apply(productID string, rules []*entities.MerchandisingRule, ranked []ScoredCandidate, opts RecommendationOptions) ([]ScoredCandidate, []*entities.MerchandisingRuleHit)
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"log"
+	"sort"
+	"sync"
+)
+
+// merchandisingService keeps the merchandising rules in memory, sorted by priority, reloading them on every write
+type merchandisingService struct {
+	repo    repositories.MerchandisingRuleRepository
+	vectors *VectorStore
+	scorer  *RecommendationService
+
+	mu        sync.RWMutex
+	rules     []*entities.MerchandisingRule
+	listeners []func()
+}
+
+// NewMerchandisingService builds the merchandising service; the vectors tell the categories of the candidates,
+// without loading their products, and the scorer resolves them against their hierarchy
+func NewMerchandisingService(repo repositories.MerchandisingRuleRepository, vectors *VectorStore, scorer *RecommendationService) MerchandisingService {
+	return &merchandisingService{repo: repo, vectors: vectors, scorer: scorer}
+}
+
+func (s *merchandisingService) GetRule(id string) (*entities.MerchandisingRule, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetAllRules lists the rules in the order they are applied
+func (s *merchandisingService) GetAllRules() ([]*entities.MerchandisingRule, error) {
+	rules, err := s.repo.GetAll()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortRules(rules)
+
+	return rules, nil
+}
+
+func (s *merchandisingService) CreateRule(rule *entities.MerchandisingRule) error {
+	if err := validateMerchandisingRule(rule); err != nil {
+		return err
+	}
+
+	if err := s.repo.Create(rule); err != nil {
+		return err
+	}
+
+	s.refresh()
+
+	return nil
+}
+
+func (s *merchandisingService) UpdateRule(rule *entities.MerchandisingRule) error {
+	if err := validateMerchandisingRule(rule); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(rule); err != nil {
+		return err
+	}
+
+	s.refresh()
+
+	return nil
+}
+
+func (s *merchandisingService) DeleteRule(id string) error {
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.refresh()
+
+	return nil
+}
+
+// Load reads the rules into memory
+func (s *merchandisingService) Load() error {
+	rules, err := s.repo.GetAll()
+
+	if err != nil {
+		return err
+	}
+
+	sortRules(rules)
+
+	s.mu.Lock()
+	s.rules = rules
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+
+	return nil
+}
+
+// Watch registers a listener told every time the rules change, e.g. to drop cached recommendations
+func (s *merchandisingService) Watch(listener func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// Rules returns the rules whose conditions the product meets, in the order they are applied
+func (s *merchandisingService) Rules(product *entities.Product) []*entities.MerchandisingRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matching := []*entities.MerchandisingRule{}
+
+	if len(s.rules) == 0 {
+		return matching
+	}
+
+	ancestry := s.scorer.CategoryAncestry(product.Categories)
+
+	for _, rule := range s.rules {
+		if len(rule.Conditions.Categories) == 0 || containsAny(rule.Conditions.Categories, ancestry) {
+			matching = append(matching, rule)
+		}
+	}
+
+	return matching
+}
+
+// Apply runs the rules over the ranked candidates of a product: exclusions first, so no other rule
+// brings an excluded product back, then boosts, which reorder the list, and pins last.
+// Pinned products that weren't candidates are added with a zero score, if the options can recommend them.
+func (s *merchandisingService) Apply(productID string, rules []*entities.MerchandisingRule, ranked []ScoredCandidate, opts RecommendationOptions) ([]ScoredCandidate, []*entities.MerchandisingRuleHit) {
+	hits := []*entities.MerchandisingRuleHit{}
+	ancestries := make(map[string][]string)
+
+	targets := func(rule *entities.MerchandisingRule, id string) bool {
+		if contains(rule.Target.ProductIDs, id) {
+			return true
+		}
+
+		if len(rule.Target.Brands) > 0 {
+			if brand := s.vectors.Brand(id); brand != "" && contains(rule.Target.Brands, brand) {
+				return true
+			}
+		}
+
+		if len(rule.Target.Categories) == 0 {
+			return false
+		}
+
+		ancestry, ok := ancestries[id]
+
+		if !ok {
+			ancestry = s.scorer.CategoryAncestry(s.vectors.Categories(id))
+			ancestries[id] = ancestry
+		}
+
+		return containsAny(rule.Target.Categories, ancestry)
+	}
+
+	excluded := func(id string) bool {
+		for _, rule := range rules {
+			if rule.Action == entities.MerchandisingExclude && targets(rule, id) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, rule := range rules {
+		if rule.Action != entities.MerchandisingExclude {
+			continue
+		}
+
+		kept := make([]ScoredCandidate, 0, len(ranked))
+		acted := []string{}
+
+		for _, candidate := range ranked {
+			if targets(rule, candidate.ID) {
+				acted = append(acted, candidate.ID)
+				continue
+			}
+
+			kept = append(kept, candidate)
+		}
+
+		ranked = kept
+		hits = appendRuleHit(hits, rule, acted)
+	}
+
+	boosted := false
+
+	for _, rule := range rules {
+		if rule.Action != entities.MerchandisingBoost {
+			continue
+		}
+
+		acted := []string{}
+
+		for i, candidate := range ranked {
+			if targets(rule, candidate.ID) {
+				ranked[i].Score *= rule.Factor
+				acted = append(acted, candidate.ID)
+			}
+		}
+
+		boosted = boosted || len(acted) > 0
+		hits = appendRuleHit(hits, rule, acted)
+	}
+
+	if boosted {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return ranked[i].Score > ranked[j].Score
+		})
+	}
+
+	pinned, pinHits := s.pin(productID, rules, ranked, excluded, opts)
+
+	return pinned, append(hits, pinHits...)
+}
+
+// pin moves the products of the pin rules to their slots, adding the ones that weren't candidates.
+// Products pinned by several rules keep the slot of the first one.
+func (s *merchandisingService) pin(productID string, rules []*entities.MerchandisingRule, ranked []ScoredCandidate, excluded func(string) bool, opts RecommendationOptions) ([]ScoredCandidate, []*entities.MerchandisingRuleHit) {
+	type pin struct {
+		productID string
+		slot      int
+		rule      *entities.MerchandisingRule
+	}
+
+	pins := []pin{}
+	seen := map[string]bool{productID: true}
+
+	for _, rule := range rules {
+		if rule.Action != entities.MerchandisingPin {
+			continue
+		}
+
+		slot := max(rule.Position, 1)
+
+		for _, id := range rule.Target.ProductIDs {
+			if seen[id] {
+				continue
+			}
+
+			seen[id] = true
+			pins = append(pins, pin{productID: id, slot: slot, rule: rule})
+			slot++
+		}
+	}
+
+	if len(pins) == 0 {
+		return ranked, nil
+	}
+
+	byID := make(map[string]ScoredCandidate, len(ranked))
+
+	for _, candidate := range ranked {
+		byID[candidate.ID] = candidate
+	}
+
+	missing := []string{}
+
+	for _, pin := range pins {
+		if _, ok := byID[pin.productID]; !ok {
+			missing = append(missing, pin.productID)
+		}
+	}
+
+	for _, id := range s.vectors.Visible(missing, opts) {
+		if !excluded(id) {
+			byID[id] = ScoredCandidate{ID: id}
+		}
+	}
+
+	rest := make([]ScoredCandidate, 0, len(ranked))
+
+	for _, candidate := range ranked {
+		if !seen[candidate.ID] {
+			rest = append(rest, candidate)
+		}
+	}
+
+	// Pins are placed from the top slot down, so each one lands where it was asked to, or last
+	sort.SliceStable(pins, func(i, j int) bool { return pins[i].slot < pins[j].slot })
+
+	hits := []*entities.MerchandisingRuleHit{}
+	acted := make(map[*entities.MerchandisingRule][]string)
+
+	for _, pin := range pins {
+		candidate, ok := byID[pin.productID]
+
+		// Deleted, unpublished or excluded products can't be pinned
+		if !ok {
+			continue
+		}
+
+		position := min(pin.slot-1, len(rest))
+		rest = append(rest[:position], append([]ScoredCandidate{candidate}, rest[position:]...)...)
+		acted[pin.rule] = append(acted[pin.rule], pin.productID)
+	}
+
+	for _, rule := range rules {
+		hits = appendRuleHit(hits, rule, acted[rule])
+	}
+
+	return rest, hits
+}
+
+func (s *merchandisingService) refresh() {
+	if err := s.Load(); err != nil {
+		log.Printf("Error reloading merchandising rules: %v", err)
+	}
+}
+
+// validateMerchandisingRule checks that the rule's target makes sense for its action
+func validateMerchandisingRule(rule *entities.MerchandisingRule) error {
+	switch {
+	case rule.Action == entities.MerchandisingPin && len(rule.Target.ProductIDs) == 0:
+		return &InvalidMerchandisingRuleError{Reason: "a pin needs the productIds of its target"}
+	case rule.Action == entities.MerchandisingPin && (len(rule.Target.Categories) > 0 || len(rule.Target.Brands) > 0):
+		return &InvalidMerchandisingRuleError{Reason: "a pin targets products by ID only"}
+	case len(rule.Target.ProductIDs) == 0 && len(rule.Target.Categories) == 0 && len(rule.Target.Brands) == 0:
+		return &InvalidMerchandisingRuleError{Reason: "the target needs productIds, categories or brands"}
+	}
+
+	return nil
+}
+
+// sortRules orders rules by priority, the oldest first between equal priorities
+func sortRules(rules []*entities.MerchandisingRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID.Hex() < rules[j].ID.Hex()
+	})
+}
+
+func appendRuleHit(hits []*entities.MerchandisingRuleHit, rule *entities.MerchandisingRule, products []string) []*entities.MerchandisingRuleHit {
+	if len(products) == 0 {
+		return hits
+	}
+
+	return append(hits, &entities.MerchandisingRuleHit{
+		RuleID:   rule.ID.Hex(),
+		Name:     rule.Name,
+		Action:   rule.Action,
+		Products: products,
+	})
+}
+
+// containsAny tells whether any of the needles is in the haystack, ignoring case
+func containsAny(haystack, needles []string) bool {
+	for _, needle := range needles {
+		if contains(haystack, needle) {
+			return true
+		}
+	}
+
+	return false
+}
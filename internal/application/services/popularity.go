@@ -0,0 +1,265 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PopularityConfig tunes how fast interactions stop counting towards a product's popularity
+type PopularityConfig struct {
+	HalfLifeHours  float64 `json:"halfLifeHours"`  // age at which an interaction counts half
+	RefreshSeconds int     `json:"refreshSeconds"` // how long computed scores are served before reading the event log again
+}
+
+func DefaultPopularityConfig() PopularityConfig {
+	return PopularityConfig{
+		HalfLifeHours:  72,
+		RefreshSeconds: 300,
+	}
+}
+
+// Validate checks that interactions decay over a positive half life
+func (c PopularityConfig) Validate() error {
+	if c.HalfLifeHours <= 0 {
+		return fmt.Errorf("popularity halfLifeHours must be positive")
+	}
+
+	return nil
+}
+
+// popularityIngestionLag is how long an event may take to be stored after it is received.
+// Each refresh reads again the events received that long before the previous one, skipping those counted already.
+const popularityIngestionLag = time.Minute
+
+// popularityHorizon is how many half lives of events are read; older ones would count less than 0.1%
+const popularityHorizon = 10
+
+// eventWeights is how much each kind of interaction says about a product's popularity
+var eventWeights = map[entities.EventType]float64{
+	entities.EventView:      1,
+	entities.EventClick:     1,
+	entities.EventAddToCart: 3,
+	entities.EventPurchase:  5,
+}
+
+// PopularityService scores products by their recent interactions, each one decaying exponentially with its age.
+// Scores are computed from the event log and cached, refreshing when they get older than the configured interval,
+// either in the background or on the first request after. A refresh decays the cached scores and only reads
+// the events ingested since the previous one, whatever their timestamp.
+type PopularityService struct {
+	events          repositories.EventRepository
+	halfLife        time.Duration
+	refreshInterval time.Duration
+
+	refreshMu   sync.Mutex                       // keeps concurrent refreshes from folding the same events in twice
+	cursor      time.Time                        // events ingested before it are counted
+	counted     map[primitive.ObjectID]time.Time // events ingested from the cursor on that are counted, by ID
+	mu          sync.Mutex
+	scores      map[string]float64
+	ranked      []ScoredCandidate // scores sorted best first
+	maxScore    float64
+	refreshedAt time.Time
+}
+
+func NewPopularityService(events repositories.EventRepository, config PopularityConfig) *PopularityService {
+	return &PopularityService{
+		events:          events,
+		halfLife:        time.Duration(config.HalfLifeHours * float64(time.Hour)),
+		refreshInterval: time.Duration(config.RefreshSeconds) * time.Second,
+	}
+}
+
+// Refresh brings the scores up to date with the event log: the previous scores are decayed to now and
+// the events ingested since the previous refresh added, or the last popularityHorizon half lives read on the first one.
+// Events decay from their own timestamp, so late ones count as little as they would have on time.
+func (p *PopularityService) Refresh() error {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	now := time.Now()
+
+	p.mu.Lock()
+	previous, refreshedAt := p.scores, p.refreshedAt
+	p.mu.Unlock()
+
+	var events []*entities.Event
+	var err error
+
+	if previous == nil {
+		events, err = p.events.GetSince(now.Add(-popularityHorizon * p.halfLife))
+	} else {
+		events, err = p.events.GetReceivedSince(p.cursor)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cursor := now.Add(-popularityIngestionLag)
+	counted := make(map[primitive.ObjectID]time.Time)
+
+	for id, receivedAt := range p.counted {
+		if !receivedAt.Before(cursor) {
+			counted[id] = receivedAt
+		}
+	}
+
+	fresh := make([]*entities.Event, 0, len(events))
+
+	for _, event := range events {
+		if _, ok := p.counted[event.ID]; ok {
+			continue
+		}
+
+		fresh = append(fresh, event)
+
+		if !event.ReceivedAt.Before(cursor) {
+			counted[event.ID] = event.ReceivedAt
+		}
+	}
+
+	scores := DecayedScores(fresh, p.halfLife, now)
+
+	decay := math.Pow(0.5, now.Sub(refreshedAt).Hours()/p.halfLife.Hours())
+
+	for id, score := range previous {
+		// Scores decayed below a single view past the horizon are forgotten, as the events behind them would be
+		if score *= decay; score >= math.Pow(0.5, popularityHorizon) {
+			scores[id] += score
+		}
+	}
+
+	p.cursor, p.counted = cursor, counted
+
+	ranked := make([]ScoredCandidate, 0, len(scores))
+
+	for id, score := range scores {
+		ranked = append(ranked, ScoredCandidate{ID: id, Score: score})
+	}
+
+	sortCandidates(ranked)
+
+	var maxScore float64
+
+	if len(ranked) > 0 {
+		maxScore = ranked[0].Score
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.scores = scores
+	p.ranked = ranked
+	p.maxScore = maxScore
+	p.refreshedAt = now
+
+	return nil
+}
+
+// Scores returns the decayed interaction score of every product with recent interactions.
+// The returned map is replaced, never modified, on refresh, so it can be read freely.
+func (p *PopularityService) Scores() (map[string]float64, error) {
+	if err := p.ensureFresh(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.scores, nil
+}
+
+// Ranked returns the products with recent interactions sorted by score, and when the scores were computed.
+// Like Scores, the slice is replaced on refresh and must not be modified.
+func (p *PopularityService) Ranked() ([]ScoredCandidate, time.Time, error) {
+	if err := p.ensureFresh(); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.ranked, p.refreshedAt, nil
+}
+
+// StartRefreshing recomputes the scores in the background every refresh interval,
+// so requests don't wait for the event log. The returned function stops it.
+func (p *PopularityService) StartRefreshing() func() {
+	if p.refreshInterval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(p.refreshInterval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.Refresh(); err != nil {
+					log.Printf("Error refreshing popularity: %v", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// Normalized returns a product's score relative to the most popular product, from 0 to 1
+func (p *PopularityService) Normalized(productID string) (float64, error) {
+	if err := p.ensureFresh(); err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.maxScore == 0 {
+		return 0, nil
+	}
+
+	return p.scores[productID] / p.maxScore, nil
+}
+
+func (p *PopularityService) ensureFresh() error {
+	p.mu.Lock()
+	stale := p.scores == nil || time.Since(p.refreshedAt) > p.refreshInterval
+	p.mu.Unlock()
+
+	if !stale {
+		return nil
+	}
+
+	return p.Refresh()
+}
+
+// DecayedScores adds up the weighted interactions of each product as of now,
+// halving the weight of an interaction every half life
+func DecayedScores(events []*entities.Event, halfLife time.Duration, now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+
+	for _, event := range events {
+		weight := eventWeights[event.Type]
+
+		if event.Type == entities.EventPurchase && event.Quantity > 1 {
+			weight *= float64(event.Quantity)
+		}
+
+		age := max(now.Sub(event.Timestamp), 0)
+
+		scores[event.ProductID] += weight * math.Pow(0.5, age.Hours()/halfLife.Hours())
+	}
+
+	return scores
+}
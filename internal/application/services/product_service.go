@@ -1,14 +1,18 @@
 package services
 
-import "backend-challenge/internal/domain/entities"
+import (
+    "backend-challenge/internal/domain/entities"
+    "backend-challenge/internal/domain/repositories"
+)
 
 
 type ProductService interface {
-    GetRecommendations(productID string) ([]*Recommendation, error)
+    GetRecommendations(productID string, opts RecommendationOptions) (*RecommendationResult, error)
+    GetAlsoBought(productID string, opts RecommendationOptions) (*RecommendationResult, error)
     ComputeFeatureVectors() map[string]map[string]float64
-    GetProductByID(id string) (*entities.Product, error)
-    GetPaginatedProducts(offset, limit int) ([]*entities.Product, error)
-    GetAllProducts() ([]*entities.Product, error)
+    GetProductByID(id string, query repositories.ProductQuery) (*entities.Product, error)
+    GetPaginatedProducts(offset, limit int, query repositories.ProductQuery) ([]*entities.Product, error)
+    GetAllProducts(query repositories.ProductQuery) ([]*entities.Product, error)
     CreateProduct(product *entities.Product) error
     UpdateProduct(product *entities.Product) error
     DeleteProduct(id string) error
@@ -3,67 +3,260 @@ package services
 import (
 	"backend-challenge/internal/domain/entities"
 	"backend-challenge/internal/domain/repositories"
+	"fmt"
+	"log"
 )
 
 type productService struct {
 	repo repositories.ProductRepository
-    recommender *RecommendationService
+    itemSimilarities repositories.ItemSimilarityRepository
+    vectors *VectorStore
+    recommenders map[string]Recommender
+    brain *BrainService
+    merchandising MerchandisingService
 }
 
-func NewProductService(repo repositories.ProductRepository, recommender *RecommendationService) ProductService {
-	return &productService{repo: repo, recommender: recommender}
+// NewProductService builds the product service; recommenders maps each strategy name to its implementation
+func NewProductService(repo repositories.ProductRepository, itemSimilarities repositories.ItemSimilarityRepository, vectors *VectorStore, recommenders map[string]Recommender, brain *BrainService, merchandising MerchandisingService) ProductService {
+	return &productService{repo: repo, itemSimilarities: itemSimilarities, vectors: vectors, recommenders: recommenders, brain: brain, merchandising: merchandising}
 }
 
-func (s *productService) GetRecommendations(productID string) ([]*Recommendation, error) {
+func (s *productService) GetRecommendations(productID string, opts RecommendationOptions) (*RecommendationResult, error) {
 
-    // Fetch the target product
-    targetProduct, err := s.repo.GetByID(productID)
+    // Fetch the target product, whether published or not
+    products, err := s.repo.GetByIDs([]string{productID}, repositories.ProductQuery{IncludeUnpublished: true})
 
     if err != nil {
         return nil, err
     }
 
-    allProducts, err := s.repo.GetAll()
+    if len(products) == 0 {
+        return nil, &ProductsNotFoundError{IDs: []string{productID}}
+    }
+
+    targetProduct := products[0]
+
+    if err := s.vectors.EnsureLoaded(); err != nil {
+        return nil, err
+    }
+
+    strategy := opts.Strategy
+
+    if strategy == "" {
+        strategy = DefaultStrategy
+    }
+
+    recommender, ok := s.recommenders[strategy]
+
+    if !ok {
+        return nil, &UnknownStrategyError{Strategy: strategy}
+    }
+
+    // The product was written outside of this service, index it now
+    if _, ok := s.vectors.Get(productID); !ok {
+        if err := s.vectors.Upsert(targetProduct); err != nil {
+            return nil, err
+        }
+    }
+
+    rules := s.merchandising.Rules(targetProduct)
+    filtered := s.brain.IsActive(opts.Boundaries, opts.Rules)
+
+    if !filtered && len(rules) == 0 {
+        ranked, err := recommender.Recommend(productID, opts)
+
+        if err != nil {
+            return nil, err
+        }
+
+        recommendations, err := hydrateCandidates(s.repo, ranked, opts)
+
+        if err != nil {
+            return nil, err
+        }
+
+        s.explain(recommender, productID, recommendations, opts)
+
+        return &RecommendationResult{Recommendations: recommendations, Source: strategy}, nil
+    }
+
+    // Merchandising, boundaries and rules reorder and drop candidates, so rank every candidate and paginate afterwards
+    rankOpts := opts
+    rankOpts.Limit, rankOpts.Offset = 0, 0
+
+    ranked, err := recommender.Recommend(productID, rankOpts)
 
     if err != nil {
         return nil, err
     }
 
-    return s.recommender.RecommendSimilarProducts(*targetProduct, allProducts), nil
+    result := &RecommendationResult{Source: strategy}
+
+    // Merchandising shapes the list before the boundaries and rules of the request narrow it down
+    ranked, result.Merchandising = s.merchandising.Apply(productID, rules, ranked, opts)
+
+    if filtered {
+        ids := make([]string, 0, len(ranked))
+
+        for _, candidate := range ranked {
+            ids = append(ids, candidate.ID)
+        }
+
+        var metadata entities.BrainMetadata
+
+        ranked, metadata = s.brain.FilterCandidates(ranked, s.vectors.Stored(ids), opts.Boundaries, opts.Rules)
+        result.Metadata = &metadata
+    }
+
+    // Only the products of the requested page are fetched
+    recommendations, err := hydrateCandidates(s.repo, paginate(ranked, opts), opts)
+
+    if err != nil {
+        return nil, err
+    }
+
+    result.Recommendations = recommendations
+
+    s.explain(recommender, productID, result.Recommendations, opts)
+
+    return result, nil
 }
 
-func (s *productService) ComputeFeatureVectors() map[string]map[string]float64 {
-    products, _ := s.repo.GetAll()
+// GetAlsoBought recommends the products most often bought or viewed together with the given one.
+// Products without co-occurrences yet fall back to content based recommendations.
+func (s *productService) GetAlsoBought(productID string, opts RecommendationOptions) (*RecommendationResult, error) {
+
+    if _, err := s.repo.GetByID(productID); err != nil {
+        return nil, err
+    }
 
-    featureVectors := make(map[string]map[string]float64)
+    similarity, err := s.itemSimilarities.GetByProductID(productID)
 
-    for _, product := range products {
-        featureVectors[product.ID.Hex()] = s.recommender.ExtractFeatureVector(*product)
+    if err != nil {
+        return nil, err
+    }
+
+    if similarity == nil || len(similarity.Neighbors) == 0 {
+        return s.GetRecommendations(productID, opts)
     }
 
-    return featureVectors
+    scored := make([]ScoredCandidate, 0, len(similarity.Neighbors))
+
+    for _, neighbor := range similarity.Neighbors {
+        if neighbor.Score >= opts.MinScore {
+            scored = append(scored, ScoredCandidate{ID: neighbor.ProductID, Score: neighbor.Score})
+        }
+    }
+
+    // Neighbors may have been deleted since the model was built, so paginate what's left
+    recommendations, err := hydrateCandidates(s.repo, scored, opts)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return &RecommendationResult{
+        Recommendations: paginate(recommendations, opts),
+        Source:          SourceCoOccurrence,
+    }, nil
 }
 
-func (s *productService) GetProductByID(id string) (*entities.Product, error) {
-    return s.repo.GetByID(id)
+// explain attaches the score breakdown to each recommendation when it was requested and the recommender can give one
+func (s *productService) explain(recommender Recommender, productID string, recommendations []*Recommendation, opts RecommendationOptions) {
+    explainer, ok := recommender.(Explainer)
+
+    if !opts.Explain || !ok {
+        return
+    }
+
+    ids := make([]string, 0, len(recommendations))
+
+    for _, recommendation := range recommendations {
+        ids = append(ids, recommendation.Product.ID.Hex())
+    }
+
+    explanations := explainer.Explain(productID, ids)
+
+    for _, recommendation := range recommendations {
+        recommendation.Explanation = explanations[recommendation.Product.ID.Hex()]
+    }
+}
+
+func (s *productService) ComputeFeatureVectors() map[string]map[string]float64 {
+    if err := s.vectors.EnsureLoaded(); err != nil {
+        log.Printf("Error loading product vectors: %v", err)
+    }
+
+    return s.vectors.Vectors(s.vectors.IDs())
+}
+
+// GetProductByID fetches a product, or fails when it doesn't exist or isn't published and the query leaves those out.
+func (s *productService) GetProductByID(id string, query repositories.ProductQuery) (*entities.Product, error) {
+    products, err := s.repo.GetByIDs([]string{id}, query)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if len(products) == 0 {
+        return nil, fmt.Errorf("product %s not found", id)
+    }
+
+    return products[0], nil
 }
 
-func (s *productService) GetPaginatedProducts(offset, limit int) ([]*entities.Product, error) {
-    return s.repo.GetPaginated(offset, limit)
+func (s *productService) GetPaginatedProducts(offset, limit int, query repositories.ProductQuery) ([]*entities.Product, error) {
+    return s.repo.GetPaginated(offset, limit, query)
 }
 
-func (s *productService) GetAllProducts() ([]*entities.Product, error) {
-    return s.repo.GetAll()
+func (s *productService) GetAllProducts(query repositories.ProductQuery) ([]*entities.Product, error) {
+    return s.repo.GetAll(query)
 }
 
 func (s *productService) CreateProduct(product *entities.Product) error {
-    return s.repo.Create(product)
+    if err := s.repo.Create(product); err != nil {
+        return err
+    }
+
+    s.refreshVector(product.ID.Hex())
+
+    return nil
 }
 
 func (s *productService) UpdateProduct(product *entities.Product) error {
-    return s.repo.Update(product)
+    if err := s.repo.Update(product); err != nil {
+        return err
+    }
+
+    s.refreshVector(product.ID.Hex())
+
+    return nil
 }
 
 func (s *productService) DeleteProduct(id string) error {
-    return s.repo.Delete(id)
-}
\ No newline at end of file
+    if err := s.repo.Delete(id); err != nil {
+        return err
+    }
+
+    if err := s.vectors.Delete(id); err != nil {
+        log.Printf("Error deleting product vector %s: %v", id, err)
+    }
+
+    return nil
+}
+
+// refreshVector recomputes the stored vector of a product after a write.
+// Updates are partial, so the full product is read back first. A failure only leaves
+// the previous vector in place, so the write itself is not failed.
+func (s *productService) refreshVector(id string) {
+    product, err := s.repo.GetByID(id)
+
+    if err != nil {
+        log.Printf("Error refreshing product vector %s: %v", id, err)
+        return
+    }
+
+    if err := s.vectors.Upsert(product); err != nil {
+        log.Printf("Error refreshing product vector %s: %v", id, err)
+    }
+}
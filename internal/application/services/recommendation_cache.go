@@ -0,0 +1,330 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"container/list"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CacheConfig sizes the cache of recommendation results
+type CacheConfig struct {
+	Size       int `json:"size"`       // results kept, the least recently used are evicted first; zero disables the cache
+	TTLSeconds int `json:"ttlSeconds"` // how long a result is served, zero keeps it until evicted or invalidated
+	// PopularityTTLSeconds is how long results of the strategies scoring popularity are served, at most TTLSeconds.
+	// Ingested events don't invalidate results, so these expire before popularity drifts far.
+	PopularityTTLSeconds int `json:"popularityTtlSeconds"`
+}
+
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		Size:                 1000,
+		TTLSeconds:           300,
+		PopularityTTLSeconds: 60,
+	}
+}
+
+// popularityStrategies are the strategies whose results move with ingested events: content scores click and
+// sold counts, hybrid blends in recent popularity on top
+var popularityStrategies = map[string]bool{StrategyContent: true, StrategyHybrid: true}
+
+// CacheStats counts how the cache has been used since startup
+type CacheStats struct {
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hitRatio"`
+	Evictions     int64   `json:"evictions"`     // results dropped to make room
+	Expirations   int64   `json:"expirations"`   // results dropped because they were older than the TTL
+	Invalidations int64   `json:"invalidations"` // results dropped because a product in them was written
+	Size          int     `json:"size"`
+	Capacity      int     `json:"capacity"`
+}
+
+// RecommendationCache is an in-process LRU cache of recommendation results with an optional TTL.
+// Every result is indexed by the products it involves, the recommended one and the recommendations,
+// so writing a product drops exactly the results that may have changed.
+type RecommendationCache struct {
+	capacity      int
+	ttl           time.Duration
+	popularityTTL time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	recency   *list.List                            // of *cacheEntry, most recently used first
+	byProduct map[string]map[*list.Element]struct{} // results each product appears in
+	stats     CacheStats
+}
+
+type cacheEntry struct {
+	key       string
+	products  []string
+	result    *RecommendationResult
+	expiresAt time.Time // zero when there is no TTL
+}
+
+func NewRecommendationCache(config CacheConfig) *RecommendationCache {
+	return &RecommendationCache{
+		capacity:      max(config.Size, 0),
+		ttl:           time.Duration(config.TTLSeconds) * time.Second,
+		popularityTTL: time.Duration(config.PopularityTTLSeconds) * time.Second,
+		entries:       make(map[string]*list.Element),
+		recency:       list.New(),
+		byProduct:     make(map[string]map[*list.Element]struct{}),
+	}
+}
+
+// Enabled tells whether results are cached at all
+func (c *RecommendationCache) Enabled() bool {
+	return c.capacity > 0
+}
+
+// Get returns the cached result for a key, counting a hit or a miss
+func (c *RecommendationCache) Get(key string) (*RecommendationResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+
+	if ok && c.expired(element.Value.(*cacheEntry)) {
+		c.remove(element)
+		c.stats.Expirations++
+		ok = false
+	}
+
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	c.recency.MoveToFront(element)
+
+	return element.Value.(*cacheEntry).result, true
+}
+
+// Put caches the result of recommending products for productID, evicting the least recently used result when full
+func (c *RecommendationCache) Put(key, productID string, result *RecommendationResult) {
+	c.put(key, productID, result, c.ttl)
+}
+
+// putPopular caches a result of a strategy scoring popularity, served for the shorter popularity TTL
+func (c *RecommendationCache) putPopular(key, productID string, result *RecommendationResult) {
+	ttl := c.ttl
+
+	if c.popularityTTL > 0 && (ttl == 0 || c.popularityTTL < ttl) {
+		ttl = c.popularityTTL
+	}
+
+	c.put(key, productID, result, ttl)
+}
+
+func (c *RecommendationCache) put(key, productID string, result *RecommendationResult, ttl time.Duration) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	entry := &cacheEntry{key: key, products: resultProducts(productID, result), result: result}
+
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	element := c.recency.PushFront(entry)
+	c.entries[key] = element
+
+	for _, id := range entry.products {
+		if c.byProduct[id] == nil {
+			c.byProduct[id] = make(map[*list.Element]struct{})
+		}
+
+		c.byProduct[id][element] = struct{}{}
+	}
+
+	for c.recency.Len() > c.capacity {
+		c.remove(c.recency.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Invalidate drops every cached result the product appears in
+func (c *RecommendationCache) Invalidate(productID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := range c.byProduct[productID] {
+		c.remove(element)
+		c.stats.Invalidations++
+	}
+}
+
+// Clear drops every cached result, counting them as invalidated
+func (c *RecommendationCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Invalidations += int64(c.recency.Len())
+
+	c.entries = make(map[string]*list.Element)
+	c.recency.Init()
+	c.byProduct = make(map[string]map[*list.Element]struct{})
+}
+
+// Stats returns the cache counters
+func (c *RecommendationCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.recency.Len()
+	stats.Capacity = c.capacity
+
+	if requests := stats.Hits + stats.Misses; requests > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(requests)
+	}
+
+	return stats
+}
+
+func (c *RecommendationCache) expired(entry *cacheEntry) bool {
+	return !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt)
+}
+
+func (c *RecommendationCache) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+
+	c.recency.Remove(element)
+	delete(c.entries, entry.key)
+
+	for _, id := range entry.products {
+		delete(c.byProduct[id], element)
+
+		if len(c.byProduct[id]) == 0 {
+			delete(c.byProduct, id)
+		}
+	}
+}
+
+// resultProducts lists the products a result depends on: the recommended product and its recommendations
+func resultProducts(productID string, result *RecommendationResult) []string {
+	products := make([]string, 0, len(result.Recommendations)+1)
+	products = append(products, productID)
+
+	for _, recommendation := range result.Recommendations {
+		products = append(products, recommendation.Product.ID.Hex())
+	}
+
+	return products
+}
+
+// cachedProductService serves recommendations from a RecommendationCache, computing them with the
+// wrapped service on a miss and invalidating them on product updates and deletes made through it.
+// Products that start qualifying for a cached result, e.g. new ones, show up once it expires,
+// and so do the changes of popularity from ingested events.
+type cachedProductService struct {
+	ProductService
+	cache *RecommendationCache
+}
+
+// NewCachedProductService wraps a product service with a recommendation cache
+func NewCachedProductService(service ProductService, cache *RecommendationCache) ProductService {
+	return &cachedProductService{ProductService: service, cache: cache}
+}
+
+func (s *cachedProductService) GetRecommendations(productID string, opts RecommendationOptions) (*RecommendationResult, error) {
+	if !s.cache.Enabled() {
+		return s.ProductService.GetRecommendations(productID, opts)
+	}
+
+	key, err := recommendationCacheKey(productID, opts)
+
+	// Options that can't be encoded, like a NaN score, are simply not cached
+	if err != nil {
+		return s.ProductService.GetRecommendations(productID, opts)
+	}
+
+	if result, ok := s.cache.Get(key); ok {
+		return copyResult(result), nil
+	}
+
+	result, err := s.ProductService.GetRecommendations(productID, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := opts.Strategy
+
+	if strategy == "" {
+		strategy = DefaultStrategy
+	}
+
+	if popularityStrategies[strategy] {
+		s.cache.putPopular(key, productID, result)
+	} else {
+		s.cache.Put(key, productID, result)
+	}
+
+	return copyResult(result), nil
+}
+
+func (s *cachedProductService) UpdateProduct(product *entities.Product) error {
+	if err := s.ProductService.UpdateProduct(product); err != nil {
+		return err
+	}
+
+	s.cache.Invalidate(product.ID.Hex())
+
+	return nil
+}
+
+func (s *cachedProductService) DeleteProduct(id string) error {
+	if err := s.ProductService.DeleteProduct(id); err != nil {
+		return err
+	}
+
+	s.cache.Invalidate(id)
+
+	return nil
+}
+
+// recommendationCacheKey identifies a request by the product and every option that affects the result
+func recommendationCacheKey(productID string, opts RecommendationOptions) (string, error) {
+	encoded, err := json.Marshal(opts)
+
+	if err != nil {
+		return "", err
+	}
+
+	return productID + ":" + string(encoded), nil
+}
+
+// copyResult returns a copy callers can annotate, e.g. with an experiment or explanations, without changing
+// the cached one. The products themselves are shared, and must not be modified.
+func copyResult(result *RecommendationResult) *RecommendationResult {
+	copied := *result
+	copied.Recommendations = make([]*Recommendation, 0, len(result.Recommendations))
+
+	for _, recommendation := range result.Recommendations {
+		recommendation := *recommendation
+		recommendation.Explanation = slices.Clone(recommendation.Explanation)
+		copied.Recommendations = append(copied.Recommendations, &recommendation)
+	}
+
+	if result.Metadata != nil {
+		metadata := *result.Metadata
+		copied.Metadata = &metadata
+	}
+
+	copied.Merchandising = slices.Clone(result.Merchandising)
+
+	return &copied
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+)
+
+const (
+	// DefaultRecommendationLimit is the number of recommendations returned when no limit is requested
+	DefaultRecommendationLimit = 5
+	// MaxRecommendationLimit caps the page size a client can request
+	MaxRecommendationLimit = 50
+)
+
+// RecommendationOptions controls which recommendations qualify and which page of them is returned
+type RecommendationOptions struct {
+	Strategy string // recommender to rank with, DefaultStrategy when empty
+	Limit    int
+	Offset   int
+	MinScore float64 // candidates must score at least this much; zero scores are never returned
+	Explain  bool    // attach the top contributing features to every recommendation
+
+	// Diversify re-ranks the results with Maximal Marginal Relevance, trading relevance for variety.
+	// Lambda goes from 0 (only variety) to 1 (only relevance).
+	Diversify bool
+	Lambda    float64
+	// MaxPerCategory limits how many results can share a category, zero means no limit
+	MaxPerCategory int
+
+	// OutOfStockPenalty scales down the score of candidates with no stock, from 0 (none) to 1 (never returned)
+	OutOfStockPenalty float64
+
+	// IncludeUnpublished also recommends the products not published yet, for back-office tools
+	IncludeUnpublished bool
+
+	// Boundaries and Rules are applied by the BrainService before paginating
+	Boundaries []entities.BrainBoundary
+	Rules      []entities.BrainRule
+}
+
+// Sources a recommendation list can come from
+const (
+	SourceContent      = "content"       // similarity of the products' attributes and text
+	SourceCoOccurrence = "co_occurrence" // products bought or viewed together
+)
+
+// RecommendationResult is a page of recommendations along with how it was produced
+type RecommendationResult struct {
+	Recommendations []*Recommendation       `json:"recommendations"`
+	Source          string                  `json:"source,omitempty"`
+	Metadata        *entities.BrainMetadata `json:"metadata,omitempty"`
+	Experiment      *ExperimentAssignment   `json:"experiment,omitempty"` // arm of the experiment the request was bucketed into
+
+	Merchandising []*entities.MerchandisingRuleHit `json:"merchandising,omitempty"` // merchandising rules that changed the list
+}
+
+func DefaultRecommendationOptions() RecommendationOptions {
+	return RecommendationOptions{
+		Limit:  DefaultRecommendationLimit,
+		Lambda: DefaultDiversityLambda,
+	}
+}
+
+// productQuery is the query loading the products the options can recommend
+func (o RecommendationOptions) productQuery() repositories.ProductQuery {
+	return repositories.ProductQuery{IncludeUnpublished: o.IncludeUnpublished}
+}
+
+// paginate applies the offset and limit of the options to an already ranked list
+func paginate[T any](items []T, opts RecommendationOptions) []T {
+	if opts.Offset >= len(items) {
+		return []T{}
+	}
+
+	items = items[opts.Offset:]
+
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		items = items[:opts.Limit]
+	}
+
+	return items
+}
@@ -2,57 +2,299 @@ package services
 
 import (
 	"backend-challenge/internal/domain/entities"
+	"fmt"
 	"math"
 	"sort"
 	"strings"
-	"unicode"
-
-	"github.com/kljensen/snowball"
+	"sync"
+	"time"
 )
 
 type Recommendation struct {
-	Product         *entities.Product `json:"product"`
-	SimilarityScore float64           `json:"similarity_score"`
+	Product         *entities.Product     `json:"product"`
+	SimilarityScore float64               `json:"similarity_score"`
+	Explanation     []FeatureContribution `json:"explanation,omitempty"`
+}
+
+// FeatureContribution is the share of a similarity score contributed by a single feature both products have
+type FeatureContribution struct {
+	Feature      string  `json:"feature"`
+	Group        string  `json:"group"`
+	Contribution float64 `json:"contribution"`
+}
+
+// productVectorVersion is bumped whenever BuildProductVector changes, so persisted vectors get recomputed
+const productVectorVersion = 9
+
+// RecommendationConfig tunes the recommender, it is loaded at startup
+type RecommendationConfig struct {
+	Analyzers map[string]AnalyzerConfig `json:"analyzers"` // lang -> analyzer settings
+	Weights   map[string]float64        `json:"weights"`   // feature group -> weight in the combined similarity
+}
+
+func DefaultRecommendationConfig() RecommendationConfig {
+	return RecommendationConfig{
+		Analyzers: map[string]AnalyzerConfig{},
+		Weights:   defaultGroupWeights(),
+	}
 }
 
-type RecommendationService struct{}
+// Validate checks that no feature group has a negative weight and that some group, counting the defaults, still counts
+func (c RecommendationConfig) Validate() error {
+	weights := defaultGroupWeights()
 
-func NewRecommendationService() *RecommendationService {
-	return &RecommendationService{}
+	for group, weight := range c.Weights {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return fmt.Errorf("recommendation weight of %s must be a non negative number", group)
+		}
+
+		weights[group] = weight
+	}
+
+	for _, weight := range weights {
+		if weight > 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("recommendation weights can't all be zero")
 }
 
-// RecommendSimilarProducts recommends similar products based on a target product
-func (s *RecommendationService) RecommendSimilarProducts(targetProduct entities.Product, allProducts []*entities.Product) []*Recommendation {
+type RecommendationService struct {
+	analyzers *AnalyzerRegistry
+	weights   map[string]float64
+
+	mu            sync.RWMutex
+	corpus        *corpusStats
+	corpusVersion int
+	categories    *CategoryTree
+}
+
+// ScoredCandidate is a candidate product ID with its similarity to the target
+type ScoredCandidate struct {
+	ID    string
+	Score float64
+}
+
+func NewRecommendationService(config RecommendationConfig) *RecommendationService {
+	weights := defaultGroupWeights()
+
+	// Groups missing from the config keep their default weight
+	for group, weight := range config.Weights {
+		weights[group] = weight
+	}
+
+	return &RecommendationService{
+		analyzers: NewAnalyzerRegistry(config.Analyzers),
+		weights:   weights,
+	}
+}
+
+// BuildCorpus recomputes the inverse document frequency table from the given catalog
+func (s *RecommendationService) BuildCorpus(products []*entities.Product) {
+	vectors := make([]*entities.ProductVector, 0, len(products))
+
+	for _, product := range products {
+		vectors = append(vectors, s.BuildProductVector(*product))
+	}
+
+	s.LoadCorpus(vectors)
+}
+
+// LoadCorpus recomputes the inverse document frequency table from precomputed product vectors
+func (s *RecommendationService) LoadCorpus(vectors []*entities.ProductVector) {
+	corpus := newCorpusStats()
+
+	for _, vector := range vectors {
+		corpus.add(vector)
+	}
+
+	s.mu.Lock()
+	s.corpus = corpus
+	s.corpusVersion++
+	s.mu.Unlock()
+}
+
+// AddToCorpus counts a product vector into the IDF table
+func (s *RecommendationService) AddToCorpus(vector *entities.ProductVector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.corpus == nil {
+		s.corpus = newCorpusStats()
+	}
+
+	s.corpus.add(vector)
+}
+
+// RemoveFromCorpus discounts a product vector previously added to the IDF table
+func (s *RecommendationService) RemoveFromCorpus(vector *entities.ProductVector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.corpus == nil {
+		return
+	}
+
+	s.corpus.remove(vector)
+}
+
+// UpdateCounts replaces the popularity counts of a product vector in the corpus.
+// Like adding and removing products, it keeps the corpus version: cached weighted vectors are refreshed
+// on their own schedule, see VectorStore.Vectors.
+func (s *RecommendationService) UpdateCounts(previous, updated *entities.ProductVector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.corpus == nil {
+		return
+	}
+
+	s.corpus.recount(previous, updated)
+}
+
+// SetCategoryTree replaces the category hierarchy products get partial credit from for sharing an ancestor
+func (s *RecommendationService) SetCategoryTree(tree *CategoryTree) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.categories = tree
+	// Weighted vectors depend on the hierarchy as much as on the IDF table
+	s.corpusVersion++
+}
+
+// RelatedCategoryFeatures returns the category features of the products worth comparing with a product
+// of the given categories, besides its own: those of their ancestors and of the categories under the same parent
+func (s *RecommendationService) RelatedCategoryFeatures(categories []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []string{}
+
+	for _, category := range categories {
+		for _, related := range append(s.categories.Ancestors(category), s.categories.Related(category)...) {
+			keys = append(keys, "category_"+related)
+		}
+	}
+
+	return keys
+}
+
+// CategoryAncestry returns the given categories, lowercased, along with all of their ancestors
+func (s *RecommendationService) CategoryAncestry(categories []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ancestry := []string{}
+
+	for _, category := range categories {
+		category = strings.ToLower(category)
+		ancestry = append(append(ancestry, category), s.categories.Ancestors(category)...)
+	}
+
+	return ancestry
+}
+
+// HasCorpus reports whether an IDF table has been built
+func (s *RecommendationService) HasCorpus() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.corpus != nil
+}
+
+// CorpusVersion changes when the whole corpus is reloaded or the category hierarchy replaced, so weighted vectors
+// can be cached against it. Products added or removed one at a time leave it as is.
+func (s *RecommendationService) CorpusVersion() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.corpusVersion
+}
+
+// RecommendSimilarProducts recommends similar products based on a target product.
+// Unpublished products are only recommended when the options include them.
+func (s *RecommendationService) RecommendSimilarProducts(targetProduct entities.Product, allProducts []*entities.Product, opts RecommendationOptions) []*Recommendation {
 	targetVector := s.ExtractFeatureVector(targetProduct)
 
-	recommendations := []*Recommendation{}
+	products := make(map[string]*entities.Product, len(allProducts))
+	candidates := make(map[string]map[string]float64, len(allProducts))
 
-	// Calculate similarity for all products
 	for _, product := range allProducts {
-		if product.ID != targetProduct.ID { // Exclude the target product itself
-			productVector := s.ExtractFeatureVector(*product)
-			similarity := s.CosineSimilarity(targetVector, productVector)
-
-			recommendations = append(recommendations, &Recommendation{
-				Product:         product,
-				SimilarityScore: similarity,
-			})
+		if !product.Published && !opts.IncludeUnpublished {
+			continue
 		}
+
+		products[product.ID.Hex()] = product
+		candidates[product.ID.Hex()] = s.ExtractFeatureVector(*product)
 	}
 
-	// Sort by similarity score
-	sort.Slice(recommendations, func(i, j int) bool {
-		return recommendations[i].SimilarityScore > recommendations[j].SimilarityScore
-	})
+	recommendations := []*Recommendation{}
 
-	// Return top 5 recommendations.
-	if len(recommendations) > 5 {
-		recommendations = recommendations[:5]
+	for _, candidate := range s.RankCandidates(targetProduct.ID.Hex(), targetVector, candidates, opts) {
+		recommendation := &Recommendation{
+			Product:         products[candidate.ID],
+			SimilarityScore: candidate.Score,
+		}
+
+		if opts.Explain {
+			recommendation.Explanation = s.Explain(targetVector, candidates[candidate.ID])
+		}
+
+		recommendations = append(recommendations, recommendation)
 	}
 
 	return recommendations
 }
 
+// RankCandidates scores precomputed candidate vectors against the target and returns the requested page of best matches,
+// diversified when the options ask for it
+func (s *RecommendationService) RankCandidates(targetID string, targetVector map[string]float64, candidates map[string]map[string]float64, opts RecommendationOptions) []ScoredCandidate {
+	scored := make([]ScoredCandidate, 0, len(candidates))
+
+	// Calculate similarity for all candidates
+	for id, vector := range candidates {
+		if id == targetID { // Exclude the target product itself
+			continue
+		}
+
+		similarity := applyStockPenalty(s.Similarity(targetVector, vector), vector, opts)
+
+		// Zero scores share nothing with the target and are never worth returning
+		if similarity <= 0 || similarity < opts.MinScore {
+			continue
+		}
+
+		scored = append(scored, ScoredCandidate{
+			ID:    id,
+			Score: similarity,
+		})
+	}
+
+	sortCandidates(scored)
+
+	return paginate(s.Diversify(scored, candidates, opts), opts)
+}
+
+// sortCandidates sorts by score, breaking ties by ID so results are stable
+func sortCandidates(scored []ScoredCandidate) {
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].ID < scored[j].ID
+	})
+}
+
+// applyStockPenalty scales down the score of a candidate without stock as much as the options ask
+func applyStockPenalty(score float64, vector map[string]float64, opts RecommendationOptions) float64 {
+	if vector[outOfStockFeature] > 0 {
+		return score * (1 - opts.OutOfStockPenalty)
+	}
+
+	return score
+}
+
 // CosineSimilarity computes the cosine similarity between two feature vectors
 func (s *RecommendationService) CosineSimilarity(vec1, vec2 map[string]float64) float64 {
 	// Extracted info on cosine similarity:
@@ -82,100 +324,138 @@ func (s *RecommendationService) CosineSimilarity(vec1, vec2 map[string]float64)
 
 // ExtractFeatureVector creates a feature vector for a product
 func (s *RecommendationService) ExtractFeatureVector(product entities.Product) map[string]float64 {
+	return s.Vectorize(s.BuildProductVector(product))
+}
+
+// BuildProductVector computes the corpus independent part of a product's features.
+// This is the expensive step (tokenizing and stemming) and is meant to be computed once per product write.
+func (s *RecommendationService) BuildProductVector(product entities.Product) *entities.ProductVector {
 	// Initialize feature map
 	features := make(map[string]float64)
 
+	categories := make([]string, 0, len(product.Categories))
+
 	// Add category features (one-hot encoding)
 	for _, category := range product.Categories {
-		features["category_"+strings.ToLower(category)] = 1.0
+		categories = append(categories, strings.ToLower(category))
+		features["category_"+strings.ToLower(category)] = categoryCredit
 	}
 
-	// Add popularity metrics
-	features["click_count"] = normalize(float64(product.ClickCount))
-	features["sold_count"] = normalize(float64(product.SoldCount))
-
-	// Add textual features (simplified; extend with NLP later)
-	nameTokens := s.tokenizeLocalizedString(product.Name.LocalizedString)
-	descriptionTokens := s.tokenizeLocalizedString(product.Description.LocalizedString)
+	inStock := false
 
-	// Combine tokens
-	tokens := append(nameTokens, descriptionTokens...)
+	// Add variant options (colors, sizes...) and stock availability
+	for _, variant := range product.Variants {
+		for _, option := range variantOptions(variant.Value) {
+			features["variant_"+option] = 1.0
+		}
 
-	
+		if variant.Stock > 0 {
+			inStock = true
+		}
+	}
 
-	for _, token := range tokens {
-		features["word_"+token] += 1.0
+	// Products without variants have no stock to speak of
+	if inStock {
+		features[inStockFeature] = 1.0
+	} else if len(product.Variants) > 0 {
+		features[outOfStockFeature] = 1.0
 	}
 
-	return features
+	return &entities.ProductVector{
+		ProductID:  product.ID,
+		Version:    productVectorVersion,
+		Categories: categories,
+		Brand:      strings.ToLower(strings.TrimSpace(product.Brand)),
+		Price:      getLowestVariantPrice(product.Variants),
+		InStock:    inStock,
+		Published:  product.Published,
+		ClickCount: product.ClickCount,
+		SoldCount:  product.SoldCount,
+		Features:   features,
+		Terms:      s.extractTerms(product),
+		CreatedAt:  product.CreatedAt,
+		UpdatedAt:  time.Now(),
+	}
 }
 
-func (s *RecommendationService) tokenizeLocalizedString(localized entities.LocalizedString) []string {
-	var tokens []string
+// Vectorize turns a precomputed product vector into the weighted feature vector used for similarity
+func (s *RecommendationService) Vectorize(vector *entities.ProductVector) map[string]float64 {
+	features := make(map[string]float64, len(vector.Features))
 
-	if localized.En != nil {
-		tokens = append(tokens, tokenize(*localized.En, "en")...)
+	for key, value := range vector.Features {
+		features[key] = value
 	}
 
-	if localized.Es != nil {
-		tokens = append(tokens, tokenize(*localized.Es, "es")...)
-	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	if localized.Pt != nil {
-		tokens = append(tokens, tokenize(*localized.Pt, "pt")...)
+	// Add the ancestors of the product's categories, unless it is in them already
+	for key, credit := range s.categories.ancestorFeatures(vector.Categories) {
+		features[key] = max(features[key], credit)
 	}
 
-	return tokens
-}
+	// Add popularity relative to the rest of the catalog
+	features["click_count"] = s.corpus.clickRank(vector.ClickCount)
+	features["sold_count"] = s.corpus.salesRank(vector.SoldCount)
 
-// normalize scales a value to a range of 0 to 1
-func normalize(value float64) float64 {
-	if value == 0 {
-		return 0
+	// Add the price band relative to the typical price of the product's categories
+	for key, value := range priceBandFeatures(vector.Price, s.corpus.medianPrice(vector.Categories)) {
+		features[key] = value
 	}
 
-	return 1.0 / (1.0 + math.Exp(-value)) // Sigmoid normalization
-}
+	// Add textual features weighted by TF-IDF
+	for lang, terms := range vector.Terms {
+		for key, count := range terms {
+			features[key] += count * s.corpus.inverseDocumentFrequency(lang, key)
+		}
+	}
 
-// Stopwords for different languages
-var stopwords = map[string]map[string]bool{
-	"en": {
-		"the": true, "is": true, "and": true, "a": true, "of": true, "to": true,
-	},
-	"es": {
-		"el": true, "es": true, "y": true, "un": true, "de": true, "para": true,
-	},
-	"pt": {
-		"o": true, "é": true, "e": true, "um": true, "de": true, "para": true,
-	},
+	return features
 }
 
+// extractTerms counts the tokens of the product name and description, grouped by language.
+// Tokens are keyed by their feature name ("name_<stem>", "description_<stem>") so each field is its own group.
+func (s *RecommendationService) extractTerms(product entities.Product) map[string]map[string]float64 {
+	terms := make(map[string]map[string]float64)
 
-// tokenize processes text by removing stopwords, handling punctuation, and applying stemming
-func tokenize(text, lang string) []string {
-	// Default to English stopwords if no language is specified
-	langStopwords, exists := stopwords[lang]
-	if !exists {
-		langStopwords = stopwords["en"]
+	fields := map[string]map[string][]string{
+		"name_":        s.tokenizeLocalizedString(product.Name.LocalizedString),
+		"description_": s.tokenizeLocalizedString(product.Description.LocalizedString),
 	}
 
-	// Split text into words and remove punctuation
-	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
-		return !unicode.IsLetter(r) && !unicode.IsNumber(r) // Remove non-alphanumeric
-	})
+	for prefix, tokensByLang := range fields {
+		for lang, tokens := range tokensByLang {
+			if len(tokens) == 0 {
+				continue
+			}
 
-	// Filter out stopwords and apply stemming
-	var tokens []string
-	for _, word := range words {
-		if !langStopwords[word] { // Skip stopwords
-			stemmed, err := snowball.Stem(word, lang, true) // Apply stemming for the specified language
-			if err == nil {
-				tokens = append(tokens, stemmed)
-			} else {
-				tokens = append(tokens, word) // Fallback to original word
+			if terms[lang] == nil {
+				terms[lang] = make(map[string]float64)
+			}
+
+			for _, token := range tokens {
+				terms[lang][prefix+token] += 1.0
 			}
 		}
 	}
 
+	return terms
+}
+
+func (s *RecommendationService) tokenizeLocalizedString(localized entities.LocalizedString) map[string][]string {
+	tokens := make(map[string][]string)
+
+	if localized.En != nil {
+		tokens["en"] = s.analyzers.Get("en").Analyze(*localized.En)
+	}
+
+	if localized.Es != nil {
+		tokens["es"] = s.analyzers.Get("es").Analyze(*localized.Es)
+	}
+
+	if localized.Pt != nil {
+		tokens["pt"] = s.analyzers.Get("pt").Analyze(*localized.Pt)
+	}
+
 	return tokens
-}
\ No newline at end of file
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/repositories"
+	"fmt"
+)
+
+// Recommendation strategies selectable per request
+const (
+	StrategyContent   = SourceContent // similarity of the products' attributes and text
+	StrategyHybrid    = "hybrid"      // content blended with co-purchases and recent popularity
+	StrategyEmbedding = "embedding"   // nearest dense embeddings learned from the catalog's text
+)
+
+// DefaultStrategy is used when a request doesn't choose one
+const DefaultStrategy = StrategyContent
+
+// Strategies lists every strategy a request can choose
+var Strategies = []string{StrategyContent, StrategyHybrid, StrategyEmbedding}
+
+// Recommender ranks the products to recommend alongside a product.
+// The product's vector must already be in the VectorStore.
+type Recommender interface {
+	// Recommend returns the requested page of scored products, best first
+	Recommend(productID string, opts RecommendationOptions) ([]ScoredCandidate, error)
+}
+
+// Explainer is implemented by recommenders that can break a recommendation's score down.
+// The candidates of a request are explained together, so what they share is looked up once.
+type Explainer interface {
+	Explain(productID string, candidateIDs []string) map[string][]FeatureContribution
+}
+
+// UnknownStrategyError is returned when a request asks for a strategy that isn't configured
+type UnknownStrategyError struct {
+	Strategy string
+}
+
+func (e *UnknownStrategyError) Error() string {
+	return fmt.Sprintf("unknown recommendation strategy %q", e.Strategy)
+}
+
+// contentRecommender recommends the products whose features are most similar, by grouped cosine similarity
+type contentRecommender struct {
+	vectors *VectorStore
+	scorer  *RecommendationService
+}
+
+func NewContentRecommender(vectors *VectorStore, scorer *RecommendationService) Recommender {
+	return &contentRecommender{vectors: vectors, scorer: scorer}
+}
+
+func (r *contentRecommender) Recommend(productID string, opts RecommendationOptions) ([]ScoredCandidate, error) {
+	targetVector, ok := r.vectors.Get(productID)
+
+	if !ok {
+		return []ScoredCandidate{}, nil
+	}
+
+	// Only products sharing a category or token with the target can score above zero
+	candidates := r.vectors.Vectors(r.vectors.Visible(r.vectors.Candidates(productID), opts))
+
+	return r.scorer.RankCandidates(productID, targetVector, candidates, opts), nil
+}
+
+func (r *contentRecommender) Explain(productID string, candidateIDs []string) map[string][]FeatureContribution {
+	vectors := r.vectors.Vectors(append([]string{productID}, candidateIDs...))
+
+	explanations := make(map[string][]FeatureContribution, len(candidateIDs))
+
+	for _, id := range candidateIDs {
+		explanations[id] = r.scorer.Explain(vectors[productID], vectors[id])
+	}
+
+	return explanations
+}
+
+// hydrateCandidates loads the products behind scored candidates, preserving their order.
+// Candidates whose product no longer exists, or the options can't recommend, are skipped.
+func hydrateCandidates(repo repositories.ProductRepository, scored []ScoredCandidate, opts RecommendationOptions) ([]*Recommendation, error) {
+	ids := make([]string, 0, len(scored))
+	scores := make(map[string]float64, len(scored))
+
+	for _, candidate := range scored {
+		ids = append(ids, candidate.ID)
+		scores[candidate.ID] = candidate.Score
+	}
+
+	products, err := repo.GetByIDs(ids, opts.productQuery())
+
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := make([]*Recommendation, 0, len(products))
+
+	for _, product := range products {
+		recommendations = append(recommendations, &Recommendation{
+			Product:         product,
+			SimilarityScore: scores[product.ID.Hex()],
+		})
+	}
+
+	return recommendations, nil
+}
@@ -0,0 +1,5 @@
+package services
+
+type SessionRecommendationService interface {
+	GetSessionRecommendations(sessionID string, opts RecommendationOptions) (*RecommendationResult, error)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"math"
+)
+
+// SourceSession marks recommendations built from a session's recent interactions
+const SourceSession = "session"
+
+const (
+	// sessionHistoryLimit is how many of a session's latest events shape its profile
+	sessionHistoryLimit = 50
+	// sessionRecencyHalfLife is how many more recent products it takes for a product to weigh half in the profile
+	sessionRecencyHalfLife = 5
+)
+
+// sessionEventWeights is how much each interaction says about what a session is looking for.
+// Other interactions only mark the product as seen.
+var sessionEventWeights = map[entities.EventType]float64{
+	entities.EventView:      1,
+	entities.EventClick:     1,
+	entities.EventAddToCart: 2,
+}
+
+type sessionRecommendationService struct {
+	events   repositories.EventRepository
+	products repositories.ProductRepository
+	vectors  *VectorStore
+	scorer   *RecommendationService
+}
+
+func NewSessionRecommendationService(events repositories.EventRepository, products repositories.ProductRepository, vectors *VectorStore, scorer *RecommendationService) SessionRecommendationService {
+	return &sessionRecommendationService{events: events, products: products, vectors: vectors, scorer: scorer}
+}
+
+// GetSessionRecommendations recommends the products most similar to a profile of what the session viewed or carted.
+// The profile is the average of those products' vectors, recent ones weighing more; products the session
+// already interacted with are never recommended.
+func (s *sessionRecommendationService) GetSessionRecommendations(sessionID string, opts RecommendationOptions) (*RecommendationResult, error) {
+	events, err := s.events.GetBySession(sessionID, sessionHistoryLimit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.vectors.EnsureLoaded(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	weights := make(map[string]float64)
+	// Products in the order the session last interacted with them, newest first
+	recent := []string{}
+
+	for _, event := range events {
+		if !seen[event.ProductID] {
+			seen[event.ProductID] = true
+			recent = append(recent, event.ProductID)
+		}
+
+		// Events are newest first, so the product's position is its recency
+		position := float64(len(recent) - 1)
+
+		weights[event.ProductID] = math.Max(weights[event.ProductID], sessionEventWeights[event.Type]*math.Pow(0.5, position/sessionRecencyHalfLife))
+	}
+
+	profile := s.profile(recent, weights)
+
+	if len(profile) == 0 {
+		return &RecommendationResult{Recommendations: []*Recommendation{}, Source: SourceSession}, nil
+	}
+
+	candidateIDs := []string{}
+
+	for _, productID := range recent {
+		if weights[productID] == 0 {
+			continue
+		}
+
+		for _, id := range s.vectors.Candidates(productID) {
+			if !seen[id] {
+				candidateIDs = append(candidateIDs, id)
+			}
+		}
+	}
+
+	candidates := s.vectors.Vectors(s.vectors.Visible(candidateIDs, opts))
+
+	recommendations, err := hydrateCandidates(s.products, s.scorer.RankCandidates("", profile, candidates, opts), opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Explain {
+		for _, recommendation := range recommendations {
+			recommendation.Explanation = s.scorer.Explain(profile, candidates[recommendation.Product.ID.Hex()])
+		}
+	}
+
+	return &RecommendationResult{Recommendations: recommendations, Source: SourceSession}, nil
+}
+
+// profile averages the vectors of the given products by their weights
+func (s *sessionRecommendationService) profile(productIDs []string, weights map[string]float64) map[string]float64 {
+	profile := make(map[string]float64)
+
+	vectors := s.vectors.Vectors(productIDs)
+
+	var total float64
+
+	for id, vector := range vectors {
+		weight := weights[id]
+
+		if weight == 0 {
+			continue
+		}
+
+		total += weight
+
+		for key, value := range vector {
+			profile[key] += weight * value
+		}
+	}
+
+	for key := range profile {
+		profile[key] /= total
+	}
+
+	return profile
+}
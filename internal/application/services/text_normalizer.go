@@ -0,0 +1,56 @@
+package services
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// htmlTagPattern matches markup tags, including their attributes
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// accentFolder decomposes characters and drops the combining marks, e.g. "ñ" -> "n", "á" -> "a"
+var accentFolder = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// NormalizeText cleans product text before tokenizing: it strips HTML markup, decodes entities,
+// removes emoji and other symbols, folds accents and lowercases, collapsing whitespace in the result.
+func NormalizeText(text string) string {
+	// Tags are replaced by a space so the words on both sides don't merge
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+
+	// Entities are decoded after stripping, so escaped markup is kept as plain text
+	text = html.UnescapeString(text)
+
+	text = strings.Map(func(r rune) rune {
+		if isSymbol(r) {
+			return ' '
+		}
+		return r
+	}, text)
+
+	folded, _, err := transform.String(accentFolder, text)
+
+	if err == nil {
+		text = folded
+	}
+
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// isSymbol reports whether a rune is an emoji, pictograph or one of the invisible characters used to compose them
+func isSymbol(r rune) bool {
+	switch {
+	case unicode.Is(unicode.So, r), unicode.Is(unicode.Sk, r): // pictographs, flags and skin tone modifiers
+		return true
+	case r == '\u200d': // zero width joiner
+		return true
+	case r >= '\ufe00' && r <= '\ufe0f': // variation selectors
+		return true
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"time"
+)
+
+const (
+	// DefaultTrendingLimit is the number of trending products returned when no limit is requested
+	DefaultTrendingLimit = 10
+	// MaxTrendingLimit caps the page size a client can request
+	MaxTrendingLimit = 50
+)
+
+// TrendingOptions filters and paginates the trending products
+type TrendingOptions struct {
+	Category string // only products in this category, case insensitive
+	StoreID  string // only products of this store
+	Limit    int
+	Offset   int
+}
+
+// TrendingProduct is a product along with its decayed interaction score
+type TrendingProduct struct {
+	Product *entities.Product `json:"product"`
+	Score   float64           `json:"trendingScore"`
+}
+
+// TrendingResult is a page of trending products and when their scores were computed
+type TrendingResult struct {
+	Products    []*TrendingProduct `json:"products"`
+	RefreshedAt time.Time          `json:"refreshedAt"`
+}
+
+type TrendingService interface {
+	GetTrending(opts TrendingOptions) (*TrendingResult, error)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+)
+
+// trendingBatchSize is how many ranked products are loaded at a time while filtering
+const trendingBatchSize = 100
+
+type trendingService struct {
+	repo       repositories.ProductRepository
+	popularity *PopularityService
+}
+
+func NewTrendingService(repo repositories.ProductRepository, popularity *PopularityService) TrendingService {
+	return &trendingService{repo: repo, popularity: popularity}
+}
+
+// GetTrending ranks the published products with recent interactions by their decayed score.
+// Products are loaded in batches, best first, until the requested page is filled.
+func (s *trendingService) GetTrending(opts TrendingOptions) (*TrendingResult, error) {
+	ranked, refreshedAt, err := s.popularity.Ranked()
+
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := opts.Offset + opts.Limit
+	trending := []*TrendingProduct{}
+
+	for start := 0; start < len(ranked) && len(trending) < wanted; start += trendingBatchSize {
+		batch := ranked[start:min(start+trendingBatchSize, len(ranked))]
+
+		ids := make([]string, 0, len(batch))
+		scores := make(map[string]float64, len(batch))
+
+		for _, candidate := range batch {
+			ids = append(ids, candidate.ID)
+			scores[candidate.ID] = candidate.Score
+		}
+
+		products, err := s.repo.GetByIDs(ids, repositories.ProductQuery{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, product := range products {
+			if !matchesTrendingFilters(product, opts) {
+				continue
+			}
+
+			trending = append(trending, &TrendingProduct{Product: product, Score: scores[product.ID.Hex()]})
+		}
+	}
+
+	return &TrendingResult{
+		Products:    paginate(trending, RecommendationOptions{Limit: opts.Limit, Offset: opts.Offset}),
+		RefreshedAt: refreshedAt,
+	}, nil
+}
+
+func matchesTrendingFilters(product *entities.Product, opts TrendingOptions) bool {
+	if opts.StoreID != "" && product.StoreID != opts.StoreID {
+		return false
+	}
+
+	if opts.Category != "" && !productMatchesCategory(product, []string{opts.Category}) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,472 @@
+package services
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"log"
+	"sync"
+	"time"
+)
+
+// VectorStore keeps every product's precomputed vector in memory, backed by a persistent collection.
+// Vectors are loaded once and then kept up to date incrementally from product writes,
+// together with an inverted index used for candidate retrieval.
+type VectorStore struct {
+	productRepo repositories.ProductRepository
+	vectorRepo  repositories.ProductVectorRepository
+	recommender *RecommendationService
+
+	mu       sync.RWMutex
+	loaded   bool
+	index    *InvertedIndex
+	vectors  map[string]*entities.ProductVector
+	weighted map[string]map[string]float64 // weighted vectors, valid for weightedVersion of the corpus
+
+	weightedVersion int
+	weightedAt      time.Time // when the cached weighted vectors started being computed
+	statsChanged    bool      // whether the corpus statistics changed since
+	listeners       []VectorListener
+
+	fillMu    sync.Mutex // lets one request at a time compute the weighted vectors missing from the cache
+	persistMu sync.Mutex // keeps count updates of the same product from being persisted out of order
+}
+
+// corpusStatsRefresh is how long the cached weighted vectors keep their weights after the corpus statistics
+// (IDF, category prices, popularity ranks) change. Those move little with each write or event batch,
+// unlike the written product's own vector, which is weighted again right away.
+const corpusStatsRefresh = time.Minute
+
+// VectorListener is told about every product vector stored or replaced, and about removed ones with a nil vector
+type VectorListener func(productID string, vector *entities.ProductVector)
+
+func NewVectorStore(productRepo repositories.ProductRepository, vectorRepo repositories.ProductVectorRepository, recommender *RecommendationService) *VectorStore {
+	return &VectorStore{
+		productRepo: productRepo,
+		vectorRepo:  vectorRepo,
+		recommender: recommender,
+		index:       NewInvertedIndex(),
+		vectors:     make(map[string]*entities.ProductVector),
+		weighted:    make(map[string]map[string]float64),
+	}
+}
+
+// Load reconciles the persisted vectors with the catalog: missing or outdated vectors are
+// recomputed, orphaned ones are removed, and the recommender corpus is rebuilt from the result.
+// Unpublished products are kept, so they can still be recommended for and, on request, recommended.
+func (v *VectorStore) Load() error {
+	products, err := v.productRepo.GetAll(repositories.ProductQuery{IncludeUnpublished: true})
+
+	if err != nil {
+		return err
+	}
+
+	persisted, err := v.vectorRepo.GetAll()
+
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*entities.ProductVector, len(persisted))
+
+	for _, vector := range persisted {
+		existing[vector.ProductID.Hex()] = vector
+	}
+
+	vectors := make(map[string]*entities.ProductVector, len(products))
+
+	for _, product := range products {
+		id := product.ID.Hex()
+
+		vector, ok := existing[id]
+		delete(existing, id)
+
+		if !ok || vector.Version != productVectorVersion {
+			vector = v.recommender.BuildProductVector(*product)
+
+			if err := v.vectorRepo.Upsert(vector); err != nil {
+				return err
+			}
+		}
+
+		vectors[id] = vector
+	}
+
+	for id := range existing {
+		if err := v.vectorRepo.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	all := make([]*entities.ProductVector, 0, len(vectors))
+	index := NewInvertedIndex()
+
+	for id, vector := range vectors {
+		all = append(all, vector)
+		index.Add(id, vector)
+	}
+
+	v.recommender.LoadCorpus(all)
+
+	v.mu.Lock()
+	previous := v.vectors
+	v.index = index
+	v.vectors = vectors
+	v.weighted = make(map[string]map[string]float64)
+	v.loaded = true
+	listeners := v.listeners
+	v.mu.Unlock()
+
+	for id, vector := range vectors {
+		notify(listeners, id, vector)
+	}
+
+	for id := range previous {
+		if _, ok := vectors[id]; !ok {
+			notify(listeners, id, nil)
+		}
+	}
+
+	log.Printf("Loaded %d product vectors", len(vectors))
+
+	return nil
+}
+
+// EnsureLoaded loads the store on first use
+func (v *VectorStore) EnsureLoaded() error {
+	v.mu.RLock()
+	loaded := v.loaded
+	v.mu.RUnlock()
+
+	if loaded {
+		return nil
+	}
+
+	return v.Load()
+}
+
+// Upsert recomputes and persists the vector of a created or updated product
+func (v *VectorStore) Upsert(product *entities.Product) error {
+	vector := v.recommender.BuildProductVector(*product)
+
+	if err := v.vectorRepo.Upsert(vector); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+
+	// Until the store is loaded the persisted copy is enough, Load will pick it up
+	if !v.loaded {
+		v.mu.Unlock()
+		return nil
+	}
+
+	id := product.ID.Hex()
+
+	if previous, ok := v.vectors[id]; ok {
+		v.recommender.RemoveFromCorpus(previous)
+	}
+
+	v.recommender.AddToCorpus(vector)
+	v.index.Add(id, vector)
+	v.vectors[id] = vector
+	delete(v.weighted, id)
+	v.statsChanged = true
+	listeners := v.listeners
+
+	v.mu.Unlock()
+
+	notify(listeners, id, vector)
+
+	return nil
+}
+
+// AddCounts updates the popularity counts of a product's vector after its counters were incremented,
+// without recomputing the rest of the vector
+func (v *VectorStore) AddCounts(productID string, clicks, sold int) error {
+	v.mu.Lock()
+
+	previous, ok := v.vectors[productID]
+
+	// Without a vector in memory, rebuild it from the already incremented product
+	if !ok {
+		v.mu.Unlock()
+
+		product, err := v.productRepo.GetByID(productID)
+
+		if err != nil {
+			return err
+		}
+
+		return v.Upsert(product)
+	}
+
+	vector := *previous
+	vector.ClickCount += clicks
+	vector.SoldCount += sold
+	vector.UpdatedAt = time.Now()
+
+	v.recommender.UpdateCounts(previous, &vector)
+	v.vectors[productID] = &vector
+	delete(v.weighted, productID)
+	v.statsChanged = true
+
+	v.mu.Unlock()
+
+	return v.persistCounts(productID)
+}
+
+// persistCounts stores the latest vector of a product whose counts changed.
+// Concurrent updates are persisted one at a time, each storing whatever is latest, so the last one wins.
+func (v *VectorStore) persistCounts(productID string) error {
+	v.persistMu.Lock()
+	defer v.persistMu.Unlock()
+
+	v.mu.RLock()
+	vector, ok := v.vectors[productID]
+	v.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return v.vectorRepo.Upsert(vector)
+}
+
+// Delete removes the vector of a deleted product
+func (v *VectorStore) Delete(productID string) error {
+	if err := v.vectorRepo.Delete(productID); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+
+	previous, ok := v.vectors[productID]
+
+	if ok {
+		v.recommender.RemoveFromCorpus(previous)
+		v.index.Remove(productID)
+		delete(v.vectors, productID)
+		delete(v.weighted, productID)
+		v.statsChanged = true
+	}
+
+	listeners := v.listeners
+
+	v.mu.Unlock()
+
+	if ok {
+		notify(listeners, productID, nil)
+	}
+
+	return nil
+}
+
+// Watch registers a listener for the vectors stored from now on and returns the ones already stored,
+// so the listener's owner can catch up without missing any change
+func (v *VectorStore) Watch(listener VectorListener) []*entities.ProductVector {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.listeners = append(v.listeners, listener)
+
+	vectors := make([]*entities.ProductVector, 0, len(v.vectors))
+
+	for _, vector := range v.vectors {
+		vectors = append(vectors, vector)
+	}
+
+	return vectors
+}
+
+// notify calls the listeners once the store's lock is released, so they can read from the store
+func notify(listeners []VectorListener, productID string, vector *entities.ProductVector) {
+	for _, listener := range listeners {
+		listener(productID, vector)
+	}
+}
+
+// IDs returns the IDs of every product in the store
+func (v *VectorStore) IDs() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	ids := make([]string, 0, len(v.vectors))
+
+	for id := range v.vectors {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// Candidates returns the IDs of the products sharing a category or token with the given product,
+// or in a category related to one of its own
+func (v *VectorStore) Candidates(productID string) []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	vector, ok := v.vectors[productID]
+
+	if !ok {
+		return []string{}
+	}
+
+	keys := append(indexKeys(vector), v.recommender.RelatedCategoryFeatures(vector.Categories)...)
+
+	candidates := make([]string, 0)
+
+	for _, id := range v.index.Lookup(keys) {
+		if id != productID {
+			candidates = append(candidates, id)
+		}
+	}
+
+	return candidates
+}
+
+// Visible keeps the IDs of the published products, or of every stored one when the options include unpublished products
+func (v *VectorStore) Visible(ids []string, opts RecommendationOptions) []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	visible := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if vector, ok := v.vectors[id]; ok && (vector.Published || opts.IncludeUnpublished) {
+			visible = append(visible, id)
+		}
+	}
+
+	return visible
+}
+
+// Stored returns the stored vectors of the given products, which must not be modified; unknown IDs are skipped
+func (v *VectorStore) Stored(ids []string) map[string]*entities.ProductVector {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	stored := make(map[string]*entities.ProductVector, len(ids))
+
+	for _, id := range ids {
+		if vector, ok := v.vectors[id]; ok {
+			stored[id] = vector
+		}
+	}
+
+	return stored
+}
+
+// Categories returns the lowercased categories of a stored product, none when it isn't stored
+func (v *VectorStore) Categories(productID string) []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if vector, ok := v.vectors[productID]; ok {
+		return vector.Categories
+	}
+
+	return []string{}
+}
+
+// Brand returns the lowercased brand of a stored product, empty when it has none or isn't stored
+func (v *VectorStore) Brand(productID string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if vector, ok := v.vectors[productID]; ok {
+		return vector.Brand
+	}
+
+	return ""
+}
+
+// Get returns the weighted feature vector of a product
+func (v *VectorStore) Get(productID string) (map[string]float64, bool) {
+	vectors := v.Vectors([]string{productID})
+
+	vector, ok := vectors[productID]
+	return vector, ok
+}
+
+// Vectors returns the weighted feature vectors of the given products; unknown IDs are skipped.
+// Weighted vectors are cached until the corpus is reloaded or the category hierarchy replaced,
+// or for corpusStatsRefresh after the corpus statistics change.
+func (v *VectorStore) Vectors(ids []string) map[string]map[string]float64 {
+	version := v.recommender.CorpusVersion()
+
+	v.mu.RLock()
+	vectors, missing := v.cachedVectors(ids, version)
+	v.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return vectors
+	}
+
+	v.fillMu.Lock()
+	defer v.fillMu.Unlock()
+
+	// Weighting is the expensive part, so it is done without keeping other requests from reading the cache
+	v.mu.RLock()
+	stale := v.weightedStale(version)
+	computed := make(map[string]map[string]float64, len(missing))
+	sources := make(map[string]*entities.ProductVector, len(missing))
+
+	for _, id := range missing {
+		// Filled by the previous request
+		if weighted, ok := v.weighted[id]; ok && !stale {
+			vectors[id] = weighted
+			continue
+		}
+
+		if vector, ok := v.vectors[id]; ok {
+			computed[id] = v.recommender.Vectorize(vector)
+			sources[id] = vector
+		}
+	}
+
+	v.mu.RUnlock()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.weightedStale(version) {
+		v.weighted = make(map[string]map[string]float64)
+		v.weightedVersion = version
+		v.weightedAt = time.Now()
+		v.statsChanged = false
+	}
+
+	for id, weighted := range computed {
+		vectors[id] = weighted
+
+		// A product written meanwhile is weighted again on its next read
+		if v.vectors[id] == sources[id] {
+			v.weighted[id] = weighted
+		}
+	}
+
+	return vectors
+}
+
+// cachedVectors looks the given products up in the weighted vector cache, returning the stored ones missing from it.
+// It must be called with the lock held.
+func (v *VectorStore) cachedVectors(ids []string, version int) (map[string]map[string]float64, []string) {
+	vectors := make(map[string]map[string]float64, len(ids))
+	missing := []string{}
+	stale := v.weightedStale(version)
+
+	for _, id := range ids {
+		if weighted, ok := v.weighted[id]; ok && !stale {
+			vectors[id] = weighted
+		} else if _, ok := v.vectors[id]; ok {
+			missing = append(missing, id)
+		}
+	}
+
+	return vectors, missing
+}
+
+// weightedStale reports whether the cached weighted vectors must be thrown away. It must be called with the lock held.
+func (v *VectorStore) weightedStale(version int) bool {
+	return version != v.weightedVersion || (v.statsChanged && time.Since(v.weightedAt) >= corpusStatsRefresh)
+}
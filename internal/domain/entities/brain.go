@@ -3,22 +3,22 @@ package entities
 import "time"
 
 type BrainBoundary struct {
-	PriceLimit          *float64
-	CategoryRestriction []string
-	OnlyInStock         bool
-	RecentlyAdded       bool
+	PriceLimit          *float64 `json:"priceLimit,omitempty"`
+	CategoryRestriction []string `json:"categoryRestriction,omitempty"`
+	OnlyInStock         bool     `json:"onlyInStock"`
+	RecentlyAdded       bool     `json:"recentlyAdded"`
 }
 
 type BrainRule struct {
-	PrioritizeCategories []string
-	SortBy               string
+	PrioritizeCategories []string `json:"prioritizeCategories,omitempty"`
+	SortBy               string   `json:"sortBy"`
 }
 
 type BrainMetadata struct {
-	ProcessingTime        time.Duration
-	ProductsConsidered    int
-	ProductsReturned      int
-	BoundariesApplied     []BrainBoundary
-	RulesApplied          []BrainRule
-	FilteredProductsCount int
+	ProcessingTime        time.Duration   `json:"processingTime"`
+	ProductsConsidered    int             `json:"productsConsidered"`
+	ProductsReturned      int             `json:"productsReturned"`
+	BoundariesApplied     []BrainBoundary `json:"boundariesApplied"`
+	RulesApplied          []BrainRule     `json:"rulesApplied"`
+	FilteredProductsCount int             `json:"filteredProductsCount"`
 }
@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventType is the kind of interaction a shopper had with a product
+type EventType string
+
+const (
+	EventClick     EventType = "click"
+	EventView      EventType = "view"
+	EventAddToCart EventType = "add_to_cart"
+	EventPurchase  EventType = "purchase"
+)
+
+// Event is an interaction of a session or user with a product
+type Event struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	EventID   string             `json:"eventId,omitempty" bson:"eventId,omitempty" validate:"required"` // generated by the client, the same when a batch is retried
+	ProductID string             `json:"productId" bson:"productId" validate:"required"`
+	Type      EventType          `json:"type" bson:"type" validate:"required,oneof=click view add_to_cart purchase"`
+	SessionID string             `json:"sessionId,omitempty" bson:"sessionId,omitempty"`
+	UserID    string             `json:"userId,omitempty" bson:"userId,omitempty"`
+	OrderID   string             `json:"orderId,omitempty" bson:"orderId,omitempty"`                    // groups the products bought together
+	Quantity  int                `json:"quantity,omitempty" bson:"quantity,omitempty" validate:"gte=0"` // units bought, purchases only
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+	// ReceivedAt is when the event was ingested, the order popularity reads the event log in
+	ReceivedAt time.Time `json:"-" bson:"receivedAt"`
+	// Experiment and Arm are stamped on ingestion when the session or user is bucketed into a running experiment
+	Experiment string `json:"experiment,omitempty" bson:"experiment,omitempty"`
+	Arm        string `json:"arm,omitempty" bson:"arm,omitempty"`
+}
+
+// Basket returns the key grouping an event with the ones that happened alongside it:
+// its order when known, otherwise its session or user
+func (e *Event) Basket() string {
+	switch {
+	case e.OrderID != "":
+		return "order:" + e.OrderID
+	case e.SessionID != "":
+		return "session:" + e.SessionID
+	case e.UserID != "":
+		return "user:" + e.UserID
+	}
+	return ""
+}
+
+// EventBatch is the body of an event ingestion request, of up to 500 events
+type EventBatch struct {
+	Events []*Event `json:"events" validate:"required,min=1,max=500,dive"`
+}
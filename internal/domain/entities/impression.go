@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Impression is a list of recommendations served to a session or user bucketed into an experiment arm
+type Impression struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Experiment  string             `json:"experiment" bson:"experiment"`
+	Arm         string             `json:"arm" bson:"arm"`
+	Unit        string             `json:"unit" bson:"unit"`           // the session or user ID that was bucketed
+	ProductID   string             `json:"productId" bson:"productId"` // the product the recommendations were for
+	Recommended []string           `json:"recommended" bson:"recommended"`
+	Timestamp   time.Time          `json:"timestamp" bson:"timestamp"`
+}
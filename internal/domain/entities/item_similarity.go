@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// ItemSimilarity lists the products most often bought or viewed together with a product,
+// as computed by the co-occurrence batch job
+type ItemSimilarity struct {
+	ProductID string         `json:"productId" bson:"_id"`
+	Neighbors []ItemNeighbor `json:"neighbors" bson:"neighbors"` // sorted by score, best first
+	UpdatedAt time.Time      `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ItemNeighbor is a product that co-occurs with another one
+type ItemNeighbor struct {
+	ProductID     string  `json:"productId" bson:"productId"`
+	Score         float64 `json:"score" bson:"score"`                 // Jaccard index of the orders or sessions of both products
+	CoOccurrences int     `json:"coOccurrences" bson:"coOccurrences"` // orders or sessions containing both products
+}
@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MerchandisingAction is what a merchandising rule does to the recommendations it applies to
+type MerchandisingAction string
+
+const (
+	MerchandisingPin     MerchandisingAction = "pin"     // place the target products at a slot
+	MerchandisingBoost   MerchandisingAction = "boost"   // scale the score of the target products, burying them below 1
+	MerchandisingExclude MerchandisingAction = "exclude" // never recommend the target products
+)
+
+// MerchandisingRule changes the recommendations served for products in some categories, after they are scored
+type MerchandisingRule struct {
+	ID         primitive.ObjectID      `json:"id" bson:"_id"`
+	Name       string                  `json:"name" bson:"name" validate:"required"`
+	Priority   int                     `json:"priority" bson:"priority"` // rules with a higher priority are applied first, and pin first
+	Conditions MerchandisingConditions `json:"conditions" bson:"conditions"`
+	Action     MerchandisingAction     `json:"action" bson:"action" validate:"required,oneof=pin boost exclude"`
+	Target     MerchandisingTarget     `json:"target" bson:"target"`
+	Factor     float64                 `json:"factor,omitempty" bson:"factor,omitempty" validate:"required_if=Action boost,gte=0"` // score multiplier of a boost
+	Position   int                     `json:"position,omitempty" bson:"position,omitempty" validate:"gte=0"`                      // 1 based slot of a pin, the top one when zero
+	CreatedAt  time.Time               `json:"createdAt,omitempty" bson:"createdAt"`
+	UpdatedAt  time.Time               `json:"updatedAt,omitempty" bson:"updatedAt"`
+}
+
+// MerchandisingConditions select the products whose recommendations a rule applies to
+type MerchandisingConditions struct {
+	Categories []string `json:"categories,omitempty" bson:"categories,omitempty"` // one of the product's categories, or their parents, must be listed; any product when empty
+}
+
+// MerchandisingTarget selects the products a rule acts on, by ID, by category or by brand
+type MerchandisingTarget struct {
+	ProductIDs []string `json:"productIds,omitempty" bson:"productIds,omitempty" validate:"dive,len=24,hexadecimal"`
+	Categories []string `json:"categories,omitempty" bson:"categories,omitempty"`
+	Brands     []string `json:"brands,omitempty" bson:"brands,omitempty"` // compared case insensitively
+}
+
+// MerchandisingRuleHit reports a rule that changed a recommendation list, and the products it acted on
+type MerchandisingRuleHit struct {
+	RuleID   string              `json:"ruleId"`
+	Name     string              `json:"name"`
+	Action   MerchandisingAction `json:"action"`
+	Products []string            `json:"products"`
+}
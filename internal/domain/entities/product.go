@@ -18,6 +18,7 @@ func init() {
 type Product struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id"`
 	StoreID     string             `json:"storeId,omitempty" bson:"storeId"`
+	Brand       string             `json:"brand,omitempty" bson:"brand,omitempty"`
 	Categories  []string           `json:"categories,omitempty" bson:"categories"`
 	Description Description        `json:"description,omitempty" bson:"description"`
 	Images      []Image            `json:"images,omitempty" bson:"images"`
@@ -33,6 +34,7 @@ type Product struct {
 
 type Category struct {
 	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id"`
+	ExternalID    string             `json:"externalId,omitempty" bson:"externalId,omitempty"` // ID in the store platform, e.g. Tiendanube
 	Name          string             `json:"name,omitempty" bson:"name" validate:"required"`
 	Subcategories []string           `json:"subcategories,omitempty" bson:"subcategories"`
 	CreatedAt     time.Time          `json:"createdAt,omitempty" bson:"createdAt"`
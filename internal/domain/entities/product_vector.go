@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductVector is the precomputed representation of a product used by the recommender.
+// Text terms, prices and popularity counts are stored raw so corpus dependent weighting (IDF, price bands
+// relative to the category median, popularity percentiles) can be applied at query time.
+type ProductVector struct {
+	ProductID  primitive.ObjectID            `json:"productId" bson:"_id"`
+	Version    int                           `json:"version" bson:"version"`
+	Categories []string                      `json:"categories" bson:"categories"`
+	Brand      string                        `json:"brand,omitempty" bson:"brand,omitempty"` // lowercased
+	Price      float64                       `json:"price" bson:"price"`                     // cheapest variant price, 0 when unknown
+	InStock    bool                          `json:"inStock" bson:"inStock"`
+	Published  bool                          `json:"published" bson:"published"`
+	ClickCount int                           `json:"clickCount" bson:"clickCount"`
+	SoldCount  int                           `json:"soldCount" bson:"soldCount"`
+	Features   map[string]float64            `json:"features" bson:"features"`
+	Terms      map[string]map[string]float64 `json:"terms" bson:"terms"`
+	CreatedAt  time.Time                     `json:"createdAt" bson:"createdAt"` // when the product was created
+	UpdatedAt  time.Time                     `json:"updatedAt" bson:"updatedAt"`
+}
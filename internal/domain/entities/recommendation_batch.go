@@ -0,0 +1,8 @@
+package entities
+
+// RecommendationBatch is the body of a batch recommendation request, seeded by up to 50 products.
+// Merge asks for a single list recommending products for all of them, instead of one list per product.
+type RecommendationBatch struct {
+	ProductIDs []string `json:"productIds" validate:"required,min=1,max=50,dive,required"`
+	Merge      bool     `json:"merge"`
+}
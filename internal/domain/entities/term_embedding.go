@@ -0,0 +1,9 @@
+package entities
+
+// TermEmbedding is the dense vector learned for a text term or category, from which product embeddings are built
+type TermEmbedding struct {
+	Term    string    `json:"term" bson:"_id"`
+	Version int       `json:"version" bson:"version"` // version of the product vectors the model was trained on
+	IDF     float64   `json:"idf" bson:"idf"`         // weight of the term, the rarer the higher
+	Vector  []float64 `json:"vector" bson:"vector"`
+}
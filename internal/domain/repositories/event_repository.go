@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"backend-challenge/internal/domain/entities"
+	"time"
+)
+
+// EventRepository is the port for storing shopper interactions
+type EventRepository interface {
+	InsertMany(events []*entities.Event) ([]*entities.Event, error)
+	GetByTypes(types []entities.EventType) ([]*entities.Event, error)
+	GetSince(since time.Time) ([]*entities.Event, error)
+	GetReceivedSince(since time.Time) ([]*entities.Event, error)
+	GetBySession(sessionID string, limit int) ([]*entities.Event, error)
+	GetByExperiment(experiment string, types []entities.EventType) ([]*entities.Event, error)
+}
@@ -0,0 +1,9 @@
+package repositories
+
+import "backend-challenge/internal/domain/entities"
+
+// ImpressionRepository is the port for the recommendation lists served in experiments
+type ImpressionRepository interface {
+	Insert(impression *entities.Impression) error
+	GetByExperiment(experiment string) ([]*entities.Impression, error)
+}
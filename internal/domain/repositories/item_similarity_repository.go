@@ -0,0 +1,9 @@
+package repositories
+
+import "backend-challenge/internal/domain/entities"
+
+// ItemSimilarityRepository is the port for the item to item similarities computed from events
+type ItemSimilarityRepository interface {
+	GetByProductID(productID string) (*entities.ItemSimilarity, error)
+	ReplaceAll(similarities []*entities.ItemSimilarity) error
+}
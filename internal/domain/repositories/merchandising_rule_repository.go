@@ -0,0 +1,12 @@
+package repositories
+
+import "backend-challenge/internal/domain/entities"
+
+// MerchandisingRuleRepository is the port for the merchandising rules applied to recommendations
+type MerchandisingRuleRepository interface {
+	GetAll() ([]*entities.MerchandisingRule, error)
+	GetByID(id string) (*entities.MerchandisingRule, error)
+	Create(rule *entities.MerchandisingRule) error
+	Update(rule *entities.MerchandisingRule) error
+	Delete(id string) error
+}
@@ -2,11 +2,18 @@ package repositories
 
 import "backend-challenge/internal/domain/entities"
 
+// ProductQuery narrows the products returned by the listings; the zero value lists what shoppers can see
+type ProductQuery struct {
+	IncludeUnpublished bool // back-office tools also list the products not published yet
+}
+
 type ProductRepository interface {
 	GetByID(id string) (*entities.Product, error)
-	GetPaginated(offset, limit int) ([]*entities.Product, error)
-	GetAll() ([]*entities.Product, error)
+	GetByIDs(ids []string, query ProductQuery) ([]*entities.Product, error)
+	GetPaginated(offset, limit int, query ProductQuery) ([]*entities.Product, error)
+	GetAll(query ProductQuery) ([]*entities.Product, error)
 	Create(product *entities.Product) error
 	Update(product *entities.Product) error
 	Delete(id string) error
+	IncrementCounters(id string, clicks, sold int) error
 }
\ No newline at end of file
@@ -0,0 +1,10 @@
+package repositories
+
+import "backend-challenge/internal/domain/entities"
+
+// ProductVectorRepository is the port for persisting precomputed product vectors
+type ProductVectorRepository interface {
+	GetAll() ([]*entities.ProductVector, error)
+	Upsert(vector *entities.ProductVector) error
+	Delete(productID string) error
+}
@@ -0,0 +1,9 @@
+package repositories
+
+import "backend-challenge/internal/domain/entities"
+
+// TermEmbeddingRepository is the port for the embedding model trained offline
+type TermEmbeddingRepository interface {
+	GetAll() ([]*entities.TermEmbedding, error)
+	ReplaceAll(terms []*entities.TermEmbedding) error
+}
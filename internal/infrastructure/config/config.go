@@ -0,0 +1,65 @@
+package config
+
+import (
+	"backend-challenge/internal/application/services"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// Config is the application configuration, read from a JSON file at startup
+type Config struct {
+	MongoURI       string                        `json:"mongoUri"`
+	Database       string                        `json:"database"`
+	Recommendation services.RecommendationConfig `json:"recommendation"`
+	CoOccurrence   services.CoOccurrenceConfig   `json:"coOccurrence"`
+	Hybrid         services.HybridConfig         `json:"hybrid"`
+	Popularity     services.PopularityConfig     `json:"popularity"`
+	Experiment     services.ExperimentConfig     `json:"experiment"`
+	Embedding      services.EmbeddingConfig      `json:"embedding"`
+	Cache          services.CacheConfig          `json:"cache"`
+	AdminToken     string                        `json:"adminToken"` // grants back-office requests their admin scope, none when empty
+}
+
+func Default() *Config {
+	return &Config{
+		MongoURI:       "mongodb://localhost:27017",
+		Database:       "backend-challenge",
+		Recommendation: services.DefaultRecommendationConfig(),
+		CoOccurrence:   services.DefaultCoOccurrenceConfig(),
+		Hybrid:         services.DefaultHybridConfig(),
+		Popularity:     services.DefaultPopularityConfig(),
+		Experiment:     services.DefaultExperimentConfig(),
+		Embedding:      services.DefaultEmbeddingConfig(),
+		Cache:          services.DefaultCacheConfig(),
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults.
+// A missing file is not an error, the defaults are used instead.
+func Load(path string) (*Config, error) {
+	config := Default()
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Config file %s not found, using defaults", path)
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Popularity.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"backend-challenge/internal/application/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzerRegistry_Stopwords(t *testing.T) {
+	registry := services.NewAnalyzerRegistry(nil)
+
+	tests := []struct {
+		lang     string
+		input    string
+		expected []string
+	}{
+		{lang: "es", input: "La linterna con los leds por la noche", expected: []string{"lintern", "leds", "noch"}},
+		{lang: "en", input: "The flashlight is one of the best and they're waterproof", expected: []string{"flashlight", "one", "best", "waterproof"}},
+		{lang: "pt", input: "A lanterna é para você", expected: []string{"lanterna"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			assert.Equal(t, tt.expected, registry.Get(tt.lang).Analyze(tt.input))
+		})
+	}
+}
+
+func TestAnalyzerRegistry_Config(t *testing.T) {
+	registry := services.NewAnalyzerRegistry(map[string]services.AnalyzerConfig{
+		"es": {
+			ExtraStopwords: []string{"producto"},
+			Synonyms:       map[string][]string{"farol": {"linterna"}},
+		},
+		"fr": {Stemmer: "french"},
+	})
+
+	assert.Equal(t, []string{"farol", "lintern", "led"}, registry.Get("es").Analyze("Producto farol LED"))
+	// languages added through config have a stemmer but no built-in stopwords
+	assert.Equal(t, []string{"lamp", "de", "poch"}, registry.Get("fr").Analyze("lampe de poche"))
+
+	// unknown languages fall back to English
+	assert.Equal(t, registry.Get("en").Analyze("the running lights"), registry.Get("de").Analyze("the running lights"))
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetBatchRecommendations(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	flashlight, headlamp, lantern := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Linterna frontal", "Linternas"), newTestProduct("Linterna recargable", "Linternas")
+	tent, bigTent := newTestProduct("Carpa iglú", "Carpas"), newTestProduct("Carpa familiar", "Carpas")
+
+	for _, p := range []*entities.Product{flashlight, headlamp, lantern, tent, bigTent} {
+		require.NoError(t, productService.CreateProduct(p))
+	}
+
+	batchService := services.NewBatchRecommendationService(productRepo, vectorStore, recommendationService)
+	seeds := []string{flashlight.ID.Hex(), headlamp.ID.Hex(), tent.ID.Hex()}
+
+	result, err := batchService.GetBatchRecommendations(seeds, false, services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	require.Nil(t, result.Merged)
+	require.Len(t, result.Results, 3)
+
+	// Per product lists match what each product's own recommendations would be
+	for _, seed := range seeds {
+		single, err := productService.GetRecommendations(seed, services.DefaultRecommendationOptions())
+		require.NoError(t, err)
+		require.Len(t, result.Results[seed].Recommendations, len(single.Recommendations))
+
+		for i, recommendation := range single.Recommendations {
+			assert.Equal(t, recommendation.Product.ID, result.Results[seed].Recommendations[i].Product.ID)
+			assert.InDelta(t, recommendation.SimilarityScore, result.Results[seed].Recommendations[i].SimilarityScore, 1e-9)
+		}
+	}
+
+	opts := services.DefaultRecommendationOptions()
+	opts.Explain = true
+
+	result, err = batchService.GetBatchRecommendations(seeds, true, opts)
+	require.NoError(t, err)
+	require.Nil(t, result.Results)
+	require.Len(t, result.Merged.Recommendations, 2, "the seeds themselves aren't recommended")
+
+	ids := []primitive.ObjectID{result.Merged.Recommendations[0].Product.ID, result.Merged.Recommendations[1].Product.ID}
+	assert.ElementsMatch(t, []primitive.ObjectID{lantern.ID, bigTent.ID}, ids)
+	assert.NotEmpty(t, result.Merged.Recommendations[0].Explanation)
+
+	_, err = batchService.GetBatchRecommendations([]string{flashlight.ID.Hex(), primitive.NewObjectID().Hex()}, true, opts)
+	var notFound *services.ProductsNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Len(t, notFound.IDs, 1)
+}
+
+func TestGetBatchRecommendationsRoute_InvalidRequests(t *testing.T) {
+	router := gin.Default()
+
+	router.POST("/recommendations/batch", recommendationHandler.GetBatchRecommendations)
+
+	validID := primitive.NewObjectID().Hex()
+
+	for _, tc := range []struct{ query, body string }{
+		{"", `{}`},
+		{"", `{"productIds": []}`},
+		{"", `{"productIds": ["not-an-id"]}`},
+		{"", `{"productIds": [""]}`},
+		{"limit=0", `{"productIds": ["` + validID + `"]}`},
+		{"strategy=hybrid", `{"productIds": ["` + validID + `"]}`},
+	} {
+		req, _ := http.NewRequest("POST", "/recommendations/batch?"+tc.query, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "expected status code 400 for %s %s", tc.query, tc.body)
+		assert.Regexp(t, "error|validationErrors", resp.Body.String())
+	}
+}
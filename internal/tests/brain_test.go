@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/adapters/web/handlers"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func brainTestProducts() (cheap, expensive, outOfStock *entities.Product) {
+	cheap = &entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Linternas"},
+		Variants:   []entities.Variant{{ID: "1", Stock: 10, Price: 100}},
+		CreatedAt:  time.Now(),
+	}
+	expensive = &entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Accesorios"},
+		Variants:   []entities.Variant{{ID: "2", Stock: 5, Price: 900}},
+		CreatedAt:  time.Now().AddDate(0, -6, 0),
+	}
+	outOfStock = &entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Linternas"},
+		Variants:   []entities.Variant{{ID: "3", Stock: 0, Price: 50}},
+		CreatedAt:  time.Now(),
+	}
+	return
+}
+
+func TestBrainService_Boundaries(t *testing.T) {
+	brainService := services.NewBrainService(15)
+	cheap, expensive, outOfStock := brainTestProducts()
+	products := []*entities.Product{cheap, expensive, outOfStock}
+
+	priceLimit := 500.0
+
+	suggestions, metadata := brainService.GenerateProductSuggestions(products, nil, []entities.BrainBoundary{{PriceLimit: &priceLimit, OnlyInStock: true}}, nil)
+	assert.Equal(t, []*entities.Product{cheap}, suggestions)
+	assert.Equal(t, 3, metadata.ProductsConsidered)
+	assert.Equal(t, 1, metadata.ProductsReturned)
+
+	suggestions, _ = brainService.GenerateProductSuggestions(products, nil, []entities.BrainBoundary{{CategoryRestriction: []string{"linternas"}}}, nil)
+	assert.Equal(t, []*entities.Product{cheap, outOfStock}, suggestions, "category restriction should be case insensitive")
+
+	suggestions, _ = brainService.GenerateProductSuggestions(products, nil, []entities.BrainBoundary{{RecentlyAdded: true}}, nil)
+	assert.NotContains(t, suggestions, expensive)
+
+	suggestions, _ = brainService.GenerateProductSuggestions(products, []*entities.Product{cheap}, nil, nil)
+	assert.NotContains(t, suggestions, cheap, "products already on the page should not be suggested")
+}
+
+func TestBrainService_FilterCandidates(t *testing.T) {
+	brainService := services.NewBrainService(15)
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	cheap, expensive, outOfStock := brainTestProducts()
+	unpriced := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Linternas"}, CreatedAt: time.Now()}
+
+	vectors := map[string]*entities.ProductVector{}
+	for _, product := range []*entities.Product{cheap, expensive, outOfStock, unpriced} {
+		vectors[product.ID.Hex()] = scorer.BuildProductVector(*product)
+	}
+
+	candidates := []services.ScoredCandidate{
+		{ID: expensive.ID.Hex(), Score: 0.9},
+		{ID: outOfStock.ID.Hex(), Score: 0.8},
+		{ID: cheap.ID.Hex(), Score: 0.7},
+		{ID: unpriced.ID.Hex(), Score: 0.6},
+		{ID: primitive.NewObjectID().Hex(), Score: 0.5}, // not stored
+	}
+
+	filtered, metadata := brainService.FilterCandidates(candidates, vectors, []entities.BrainBoundary{{OnlyInStock: true}}, []entities.BrainRule{{PrioritizeCategories: []string{"Linternas"}}})
+
+	assert.Equal(t, []services.ScoredCandidate{{ID: cheap.ID.Hex(), Score: 0.7}, {ID: expensive.ID.Hex(), Score: 0.9}}, filtered, "prioritized category should come first")
+	assert.Equal(t, 5, metadata.ProductsConsidered)
+	assert.Equal(t, 2, metadata.ProductsReturned)
+
+	sorted, _ := brainService.FilterCandidates(candidates[:3], vectors, nil, []entities.BrainRule{{SortBy: "price"}})
+	assert.Equal(t, []string{outOfStock.ID.Hex(), cheap.ID.Hex(), expensive.ID.Hex()}, []string{sorted[0].ID, sorted[1].ID, sorted[2].ID})
+
+	priceLimit := 500.0
+
+	bounded, _ := brainService.FilterCandidates(candidates, vectors, []entities.BrainBoundary{{PriceLimit: &priceLimit, RecentlyAdded: true}}, nil)
+	assert.Equal(t, []services.ScoredCandidate{{ID: outOfStock.ID.Hex(), Score: 0.8}, {ID: cheap.ID.Hex(), Score: 0.7}}, bounded, "products without a price are over any limit")
+}
+
+func TestGetRecommendations_BoundariesLoadOnlyThePage(t *testing.T) {
+	flashlight := func(name string, price float64, stock int) *entities.Product {
+		product := newTestProduct(name, "Linternas")
+		product.Variants = []entities.Variant{{ID: name, Stock: stock, Price: price}}
+		return product
+	}
+
+	target := flashlight("Linterna táctica", 300, 1)
+	cheapest := flashlight("Linterna de bolsillo", 50, 2)
+	catalog := []*entities.Product{target, flashlight("Linterna frontal", 200, 3), cheapest, flashlight("Linterna solar", 10, 0), flashlight("Linterna farol", 150, 4)}
+
+	productRepo := &hydrationCountingRepository{ProductRepository: memory.NewProductRepository(catalog)}
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+
+	productService := services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	}, services.NewBrainService(15), services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer))
+
+	require.NoError(t, vectors.Load())
+	productRepo.loaded = nil
+
+	opts := services.DefaultRecommendationOptions()
+	opts.Limit = 1
+	opts.Boundaries = []entities.BrainBoundary{{OnlyInStock: true}}
+	opts.Rules = []entities.BrainRule{{SortBy: "price"}}
+
+	result, err := productService.GetRecommendations(target.ID.Hex(), opts)
+	require.NoError(t, err)
+
+	require.Len(t, result.Recommendations, 1)
+	assert.Equal(t, cheapest.ID, result.Recommendations[0].Product.ID, "the product without stock is left out before sorting")
+	require.NotNil(t, result.Metadata)
+	assert.Equal(t, 3, result.Metadata.ProductsReturned)
+	assert.Equal(t, []int{1, 1}, productRepo.loaded, "the product itself, then boundaries and rules are applied before the page is fetched")
+}
+
+func TestBrainService_IsActive(t *testing.T) {
+	brainService := services.NewBrainService(15)
+
+	assert.False(t, brainService.IsActive([]entities.BrainBoundary{{}}, []entities.BrainRule{{SortBy: "none"}}))
+	assert.True(t, brainService.IsActive([]entities.BrainBoundary{{OnlyInStock: true}}, nil))
+	assert.True(t, brainService.IsActive(nil, []entities.BrainRule{{SortBy: "price"}}))
+}
+
+func TestGetAllProductsRoute_Suggestions(t *testing.T) {
+	cheap, expensive, outOfStock := brainTestProducts()
+
+	for _, product := range []*entities.Product{cheap, expensive, outOfStock} {
+		product.Published = true
+	}
+
+	productRepo := memory.NewProductRepository([]*entities.Product{cheap, expensive, outOfStock})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	brainService := services.NewBrainService(15)
+
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+
+	productService := services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors,
+		map[string]services.Recommender{}, brainService, services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer))
+	experiments := services.NewExperimentService(services.DefaultExperimentConfig(), memory.NewImpressionRepository(), memory.NewEventRepository(nil))
+
+	router := gin.Default()
+	router.GET("/products", handlers.NewProductHandler(productService, brainService, experiments).GetAllProducts)
+
+	req, _ := http.NewRequest("GET", "/products?onlyInStock=true", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Products               []*entities.Product    `json:"products"`
+		Recommendations        []*entities.Product    `json:"recommendations"`
+		RecommendationMetadata map[string]interface{} `json:"recommendationMetadata"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+
+	assert.Len(t, body.Products, 3)
+
+	require.Len(t, body.Recommendations, 2, "suggestions leave out the products without stock")
+	assert.Equal(t, []primitive.ObjectID{cheap.ID, expensive.ID}, []primitive.ObjectID{body.Recommendations[0].ID, body.Recommendations[1].ID})
+
+	assert.Equal(t, 3.0, body.RecommendationMetadata["productsConsidered"])
+	assert.Contains(t, body.RecommendationMetadata, "boundariesApplied")
+
+	req, _ = http.NewRequest("GET", "/products?priceLimit=cheap", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testCategoryTree() *services.CategoryTree {
+	tents := &entities.Category{ID: primitive.NewObjectID(), Name: "CARPAS"}
+	headlamps := &entities.Category{ID: primitive.NewObjectID(), Name: "FRONTALES"}
+
+	return services.NewCategoryTree([]*entities.Category{
+		{ID: primitive.NewObjectID(), Name: "ACCESORIOS", Subcategories: []string{"Linternas", tents.ID.Hex(), primitive.NewObjectID().Hex()}},
+		{ID: primitive.NewObjectID(), Name: "LINTERNAS", Subcategories: []string{"frontales"}},
+		tents,
+		headlamps,
+		{ID: primitive.NewObjectID(), Name: "HOGAR", Subcategories: []string{"lamparas", "hogar"}},
+		{ID: primitive.NewObjectID(), Name: "LAMPARAS", Subcategories: []string{"hogar"}},
+	})
+}
+
+func TestCategoryTree(t *testing.T) {
+	tree := testCategoryTree()
+
+	assert.Equal(t, []string{"linternas", "accesorios"}, tree.Ancestors("frontales"), "subcategories are referenced by name or ID")
+	assert.Equal(t, []string{"accesorios"}, tree.Ancestors("carpas"))
+	assert.Empty(t, tree.Ancestors("accesorios"))
+	assert.Empty(t, tree.Ancestors("unknown"))
+
+	assert.Equal(t, []string{"accesorios"}, tree.Ancestors("linternas"), "categories without a document of their own are referenced by name")
+
+	assert.ElementsMatch(t, []string{"carpas", "frontales"}, tree.Related("linternas"))
+	assert.ElementsMatch(t, []string{"linternas", "carpas", "frontales"}, tree.Related("accesorios"))
+
+	// Loops in the hierarchy don't hang
+	assert.Equal(t, []string{"hogar"}, tree.Ancestors("lamparas"))
+	assert.Equal(t, []string{"lamparas"}, tree.Ancestors("hogar"))
+	assert.Empty(t, tree.Related("unknown"))
+}
+
+func TestCategoryTree_StorePlatformIDs(t *testing.T) {
+	tree := services.NewCategoryTree([]*entities.Category{
+		{ID: primitive.NewObjectID(), ExternalID: "4280789", Name: "ACCESORIOS", Subcategories: []string{"4386530", "4386329"}},
+		{ID: primitive.NewObjectID(), ExternalID: "4386530", Name: "LINTERNAS"},
+	})
+
+	assert.Equal(t, []string{"accesorios"}, tree.Ancestors("linternas"), "seeded subcategories are referenced by the store platform's ID")
+	assert.Equal(t, []string{"linternas"}, tree.Related("accesorios"), "unknown IDs are left out rather than taken for names")
+}
+
+func TestContentRecommender_SharedAncestors(t *testing.T) {
+	flashlight := newTestProduct("Linterna táctica", "LINTERNAS")
+	headlamp := newTestProduct("Vincha luminosa", "FRONTALES")
+	tent := newTestProduct("Carpa iglú", "CARPAS")
+	lamp := newTestProduct("Velador de mesa", "LAMPARAS")
+	otherFlashlight := newTestProduct("Linterna de mano", "LINTERNAS")
+
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(memory.NewProductRepository([]*entities.Product{flashlight, headlamp, tent, lamp, otherFlashlight}), memory.NewProductVectorRepository(), scorer)
+	require.NoError(t, vectors.Load())
+
+	recommender := services.NewContentRecommender(vectors, scorer)
+
+	ids := func(scored []services.ScoredCandidate) []string {
+		ids := []string{}
+		for _, candidate := range scored {
+			ids = append(ids, candidate.ID)
+		}
+		return ids
+	}
+
+	recommendations, err := recommender.Recommend(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.Equal(t, []string{otherFlashlight.ID.Hex()}, ids(recommendations), "flat categories only match exactly")
+
+	scorer.SetCategoryTree(testCategoryTree())
+
+	recommendations, err = recommender.Recommend(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.Equal(t, []string{otherFlashlight.ID.Hex(), headlamp.ID.Hex(), tent.ID.Hex()}, ids(recommendations),
+		"subcategories score above siblings, and unrelated categories not at all")
+
+	recommendations, err = recommender.Recommend(tent.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	require.Len(t, recommendations, 3)
+	assert.ElementsMatch(t, []string{flashlight.ID.Hex(), otherFlashlight.ID.Hex()}, ids(recommendations)[:2])
+	assert.Equal(t, headlamp.ID.Hex(), recommendations[2].ID, "products sharing a grandparent get less credit")
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildItemSimilarities(t *testing.T) {
+	events := []*entities.Event{
+		// Flashlights and batteries are bought together in two orders
+		{ProductID: "linterna", Type: entities.EventPurchase, OrderID: "o1", SessionID: "s1"},
+		{ProductID: "pilas", Type: entities.EventPurchase, OrderID: "o1", SessionID: "s1"},
+		{ProductID: "linterna", Type: entities.EventPurchase, OrderID: "o2"},
+		{ProductID: "pilas", Type: entities.EventPurchase, OrderID: "o2"},
+		{ProductID: "pilas", Type: entities.EventPurchase, OrderID: "o3"},
+		// Flashlights and lanterns are only viewed together once
+		{ProductID: "linterna", Type: entities.EventView, SessionID: "s2"},
+		{ProductID: "farol", Type: entities.EventView, SessionID: "s2"},
+		{ProductID: "linterna", Type: entities.EventView, SessionID: "s2"},
+		// Anonymous events can't be related
+		{ProductID: "farol", Type: entities.EventView},
+		{ProductID: "pilas", Type: entities.EventView},
+	}
+
+	similarities := services.BuildItemSimilarities(events, services.CoOccurrenceConfig{MinCoOccurrences: 1})
+
+	byProduct := map[string]*entities.ItemSimilarity{}
+	for _, similarity := range similarities {
+		byProduct[similarity.ProductID] = similarity
+	}
+
+	require.Contains(t, byProduct, "linterna")
+	require.Len(t, byProduct["linterna"].Neighbors, 2)
+
+	// linterna is in o1, o2 and s2; pilas in o1, o2 and o3: 2 shared baskets out of 4
+	assert.Equal(t, "pilas", byProduct["linterna"].Neighbors[0].ProductID)
+	assert.InDelta(t, 0.5, byProduct["linterna"].Neighbors[0].Score, 1e-9)
+	assert.Equal(t, 2, byProduct["linterna"].Neighbors[0].CoOccurrences)
+
+	// farol is only in s2, which linterna is also in: 1 shared basket out of 3
+	assert.Equal(t, "farol", byProduct["linterna"].Neighbors[1].ProductID)
+	assert.InDelta(t, 1.0/3, byProduct["linterna"].Neighbors[1].Score, 1e-9)
+
+	similarities = services.BuildItemSimilarities(events, services.CoOccurrenceConfig{MinCoOccurrences: 2, MaxNeighbors: 5})
+
+	require.Len(t, similarities, 2, "farol only co-occurs once, so it's left out")
+	assert.Equal(t, "linterna", similarities[0].ProductID)
+	assert.Equal(t, "pilas", similarities[1].ProductID)
+}
+
+func TestGetAlsoBought(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	flashlight, batteries, tent := newTestProduct("Linterna", "Camping"), newTestProduct("Pilas", "Camping"), newTestProduct("Carpa", "Camping")
+
+	for _, p := range []*entities.Product{flashlight, batteries, tent} {
+		require.NoError(t, productService.CreateProduct(p))
+	}
+
+	require.NoError(t, itemSimilarityRepo.ReplaceAll([]*entities.ItemSimilarity{{
+		ProductID: flashlight.ID.Hex(),
+		Neighbors: []entities.ItemNeighbor{{ProductID: batteries.ID.Hex(), Score: 0.5, CoOccurrences: 2}},
+	}}))
+
+	result, err := productService.GetAlsoBought(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.Equal(t, services.SourceCoOccurrence, result.Source)
+	require.Len(t, result.Recommendations, 1)
+	assert.Equal(t, batteries.ID, result.Recommendations[0].Product.ID)
+	assert.Equal(t, 0.5, result.Recommendations[0].SimilarityScore)
+
+	// Nobody bought the tent with anything yet, so it's recommended by content
+	result, err = productService.GetAlsoBought(tent.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.Equal(t, services.SourceContent, result.Source)
+	assert.NotEmpty(t, result.Recommendations)
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHNSW_Search(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	randomUnitVector := func() []float64 {
+		vector := make([]float64, 16)
+		var norm float64
+		for i := range vector {
+			vector[i] = rng.NormFloat64()
+			norm += vector[i] * vector[i]
+		}
+		for i := range vector {
+			vector[i] /= math.Sqrt(norm)
+		}
+		return vector
+	}
+
+	index := services.NewHNSW(8, 100)
+	vectors := map[string][]float64{}
+
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("p%d", i)
+		vectors[id] = randomUnitVector()
+		index.Add(id, vectors[id])
+	}
+
+	index.Remove("p0")
+	delete(vectors, "p0")
+
+	var found, expected int
+
+	for q := 0; q < 20; q++ {
+		query := randomUnitVector()
+
+		exact := make([]services.ScoredCandidate, 0, len(vectors))
+		for id, vector := range vectors {
+			var dot float64
+			for i := range vector {
+				dot += vector[i] * query[i]
+			}
+			exact = append(exact, services.ScoredCandidate{ID: id, Score: dot})
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].Score > exact[j].Score })
+
+		results := index.Search(query, 10, 64)
+		require.Len(t, results, 10)
+
+		approximate := map[string]bool{}
+		for i, result := range results {
+			assert.NotEqual(t, "p0", result.ID, "removed vectors are never returned")
+			if i > 0 {
+				assert.GreaterOrEqual(t, results[i-1].Score, result.Score, "results are sorted best first")
+			}
+			approximate[result.ID] = true
+		}
+
+		for _, candidate := range exact[:10] {
+			expected++
+			if approximate[candidate.ID] {
+				found++
+			}
+		}
+	}
+
+	assert.GreaterOrEqual(t, float64(found)/float64(expected), 0.9, "the index should find most of the true nearest neighbors")
+	assert.Equal(t, 999, index.Len())
+
+	// Replacing a vector moves it
+	index.Add("p1", vectors["p2"])
+	results := index.Search(vectors["p2"], 2, 64)
+	assert.ElementsMatch(t, []string{"p1", "p2"}, []string{results[0].ID, results[1].ID})
+	assert.Equal(t, 999, index.Len())
+}
+
+func TestHNSW_Updates(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	randomUnitVector := func() []float64 {
+		vector := make([]float64, 16)
+		var norm float64
+		for i := range vector {
+			vector[i] = rng.NormFloat64()
+			norm += vector[i] * vector[i]
+		}
+		for i := range vector {
+			vector[i] /= math.Sqrt(norm)
+		}
+		return vector
+	}
+
+	index := services.NewHNSW(8, 100)
+	vectors := map[string][]float64{}
+
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("p%d", i)
+		vectors[id] = randomUnitVector()
+		index.Add(id, vectors[id])
+	}
+
+	// Products are upserted again on every change, most of the time with the same embedding
+	for round := 0; round < 5; round++ {
+		for id, vector := range vectors {
+			index.Add(id, vector)
+		}
+	}
+
+	// Moving every vector several times over rebuilds the graph without the replaced nodes
+	for round := 0; round < 5; round++ {
+		for id := range vectors {
+			vectors[id] = randomUnitVector()
+			index.Add(id, vectors[id])
+		}
+	}
+
+	assert.Equal(t, 200, index.Len())
+
+	for id, vector := range vectors {
+		results := index.Search(vector, 1, 32)
+		require.Len(t, results, 1)
+		assert.Equal(t, id, results[0].ID, "every vector should be found at its latest position")
+	}
+}
+
+func TestEmbeddingRecommender(t *testing.T) {
+	// "linterna" and "farol" never appear together, but share the rest of their vocabulary
+	flashlight := newTestProduct("Linterna led recargable potente", "Iluminacion")
+	products := []*entities.Product{
+		flashlight,
+		newTestProduct("Linterna led recargable táctica", "Iluminacion"),
+		newTestProduct("Farol led recargable camping", "Iluminacion"),
+		newTestProduct("Farol led potente camping", "Iluminacion"),
+		newTestProduct("Carpa iglú impermeable", "Camping"),
+		newTestProduct("Carpa familiar impermeable", "Camping"),
+		newTestProduct("Bolsa de dormir impermeable", "Camping"),
+		newTestProduct("Mochila impermeable camping", "Camping"),
+	}
+
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	productRepo := memory.NewProductRepository(products)
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	termEmbeddingRepo := memory.NewTermEmbeddingRepository()
+
+	config := services.DefaultEmbeddingConfig()
+	config.Dimensions = 4
+
+	index := services.NewEmbeddingIndex(termEmbeddingRepo, vectors, config)
+	recommender := services.NewEmbeddingRecommender(index, vectors, scorer)
+
+	recommendations, err := recommender.Recommend(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	require.NotEmpty(t, recommendations)
+
+	terms, err := termEmbeddingRepo.GetAll()
+	require.NoError(t, err)
+	assert.NotEmpty(t, terms, "a model trained on load is stored for the next start")
+
+	ranks := map[string]int{}
+	for i, recommendation := range recommendations {
+		ranks[recommendation.ID] = i
+	}
+
+	lantern := products[2].ID.Hex()
+	tent := products[4].ID.Hex()
+
+	require.Contains(t, ranks, lantern, "lanterns relate to flashlights through their shared context")
+	if rank, ok := ranks[tent]; ok {
+		assert.Less(t, ranks[lantern], rank)
+	}
+
+	// Products written after loading are embedded and indexed right away
+	headlamp := newTestProduct("Linterna frontal led recargable", "Iluminacion")
+	require.NoError(t, productRepo.Create(headlamp))
+	require.NoError(t, vectors.Upsert(headlamp))
+
+	recommendations, err = recommender.Recommend(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.True(t, containsRecommendation(recommendations, headlamp.ID.Hex()))
+
+	require.NoError(t, vectors.Delete(headlamp.ID.Hex()))
+
+	recommendations, err = recommender.Recommend(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.False(t, containsRecommendation(recommendations, headlamp.ID.Hex()), "deleted products aren't recommended")
+}
+
+func containsRecommendation(recommendations []services.ScoredCandidate, productID string) bool {
+	for _, recommendation := range recommendations {
+		if recommendation.ID == productID {
+			return true
+		}
+	}
+	return false
+}
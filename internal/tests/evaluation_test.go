@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEvaluationQueries(t *testing.T) {
+	queries := services.EvaluationQueries([]*entities.Event{
+		{ProductID: "a", Type: entities.EventPurchase, OrderID: "o1"},
+		{ProductID: "b", Type: entities.EventPurchase, OrderID: "o1"},
+		{ProductID: "b", Type: entities.EventPurchase, OrderID: "o1"},
+		{ProductID: "c", Type: entities.EventView, SessionID: "s1"},
+		{ProductID: "d", Type: entities.EventView},
+	})
+
+	assert.Equal(t, []services.EvaluationQuery{
+		{Seed: "a", Relevant: []string{"b"}},
+		{Seed: "b", Relevant: []string{"a"}},
+	}, queries, "baskets of a single product and events without a basket can't be evaluated")
+}
+
+func TestEvaluate(t *testing.T) {
+	flashlight, headlamp, tent, bigTent := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Linterna frontal", "Linternas"), newTestProduct("Carpa iglú", "Carpas"), newTestProduct("Carpa familiar", "Carpas")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, tent, bigTent})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+
+	evaluator := services.NewEvaluator(vectors, scorer, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	})
+
+	report, err := evaluator.Evaluate([]services.EvaluationQuery{
+		{Seed: flashlight.ID.Hex(), Relevant: []string{headlamp.ID.Hex()}},
+		{Seed: tent.ID.Hex(), Relevant: []string{flashlight.ID.Hex(), primitive.NewObjectID().Hex()}},
+		{Seed: primitive.NewObjectID().Hex(), Relevant: []string{tent.ID.Hex()}},
+	}, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.K)
+	assert.Equal(t, 4, report.Products)
+	assert.Equal(t, 2, report.Queries, "queries for products outside the catalog are skipped")
+
+	// The flashlight's only match is the relevant headlamp, while the tent's only match is a miss
+	metrics := report.Strategies[services.StrategyContent]
+	assert.InDelta(t, 0.25, metrics.Precision, 1e-4)
+	assert.InDelta(t, 0.5, metrics.Recall, 1e-4)
+	assert.InDelta(t, 0.5, metrics.NDCG, 1e-4)
+	assert.InDelta(t, 0.5, metrics.Coverage, 1e-4)
+	assert.Zero(t, metrics.Diversity, "single product lists have no diversity to measure")
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/adapters/web/handlers"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecordEvents(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	product := &entities.Product{
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Linterna táctica")}},
+		ClickCount: 5,
+		SoldCount:  1,
+	}
+	require.NoError(t, productRepo.Create(product))
+
+	router := gin.Default()
+	router.POST("/events", GetEventHandler().RecordEvents)
+
+	body := `{"events": [
+		{"productId": "` + product.ID.Hex() + `", "type": "view", "sessionId": "s1", "eventId": "e1"},
+		{"productId": "` + product.ID.Hex() + `", "type": "click", "sessionId": "s1", "eventId": "e2"},
+		{"productId": "` + product.ID.Hex() + `", "type": "click", "sessionId": "s2", "eventId": "e3"},
+		{"productId": "` + product.ID.Hex() + `", "type": "add_to_cart", "sessionId": "s1", "eventId": "e4"},
+		{"productId": "` + product.ID.Hex() + `", "type": "purchase", "sessionId": "s1", "quantity": 3, "eventId": "e5"},
+		{"productId": "` + product.ID.Hex() + `", "type": "purchase", "userId": "u1", "eventId": "e6"}
+	]}`
+
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	updated, err := productRepo.GetByID(product.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, 7, updated.ClickCount, "clicks should be added to the existing count")
+	assert.Equal(t, 5, updated.SoldCount, "purchases count every unit, one when no quantity is given")
+
+	stored, err := testDB.Collection("events").CountDocuments(context.TODO(), bson.M{"productId": product.ID.Hex()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stored)
+
+	req, _ = http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	updated, err = productRepo.GetByID(product.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, 7, updated.ClickCount, "a retried batch shouldn't be counted twice")
+
+	stored, err = testDB.Collection("events").CountDocuments(context.TODO(), bson.M{"productId": product.ID.Hex()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stored)
+}
+
+func TestRecordEvents_Retries(t *testing.T) {
+	flashlight := newTestProduct("Linterna táctica", "Linternas")
+	flashlight.ClickCount = 5
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	require.NoError(t, vectors.Load())
+
+	eventRepo := memory.NewEventRepository(nil)
+	experiments := services.NewExperimentService(services.DefaultExperimentConfig(), memory.NewImpressionRepository(), eventRepo)
+
+	router := gin.Default()
+	router.POST("/events", handlers.NewEventHandler(services.NewEventService(eventRepo, productRepo, vectors, experiments)).RecordEvents)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	body := `{"events": [
+		{"productId": "` + flashlight.ID.Hex() + `", "type": "click", "sessionId": "s1", "eventId": "e1"},
+		{"productId": "` + flashlight.ID.Hex() + `", "type": "click", "sessionId": "s1", "eventId": "e1"},
+		{"productId": "` + flashlight.ID.Hex() + `", "type": "click", "sessionId": "s2", "eventId": "e2"}
+	]}`
+
+	resp := post(body)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.JSONEq(t, `{"recorded": 2, "duplicates": 1}`, resp.Body.String())
+
+	resp = post(body)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.JSONEq(t, `{"recorded": 0, "duplicates": 3}`, resp.Body.String())
+
+	updated, err := productRepo.GetByID(flashlight.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, 7, updated.ClickCount, "each event ID is counted once")
+
+	resp = post(`{"events": [
+		{"productId": "` + flashlight.ID.Hex() + `", "type": "click", "eventId": "e3"},
+		{"productId": "` + primitive.NewObjectID().Hex() + `", "type": "click", "eventId": "e4"}
+	]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code, "unknown products reject the whole batch")
+
+	updated, err = productRepo.GetByID(flashlight.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, 7, updated.ClickCount)
+}
+
+func TestRecordEvents_InvalidBatch(t *testing.T) {
+	router := gin.Default()
+	router.POST("/events", GetEventHandler().RecordEvents)
+
+	productID := primitive.NewObjectID().Hex()
+
+	for _, body := range []string{
+		`{"events": []}`,
+		`{"events": [{"productId": "` + productID + `", "type": "like", "eventId": "e1"}]}`,
+		`{"events": [{"type": "click", "eventId": "e1"}]}`,
+		`{"events": [{"productId": "not-an-id", "type": "click", "eventId": "e1"}]}`,
+		`{"events": [{"productId": "` + productID + `", "type": "purchase", "quantity": -1, "eventId": "e1"}]}`,
+		`{"events": [{"productId": "` + productID + `", "type": "click"}]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "expected status code 400 for %s", body)
+	}
+}
+
+func TestRecordEvents_PopularityCounts(t *testing.T) {
+	product := func(name string, clicks int) *entities.Product {
+		product := newTestProduct(name, "Linternas")
+		product.ClickCount = clicks
+		return product
+	}
+
+	flashlight, headlamp, lantern := product("Linterna táctica", 1), product("Linterna frontal", 5), product("Farol", 10)
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, lantern})
+	vectorRepo := memory.NewProductVectorRepository()
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, vectorRepo, scorer)
+	require.NoError(t, vectors.Load())
+
+	experiments := services.NewExperimentService(services.DefaultExperimentConfig(), memory.NewImpressionRepository(), memory.NewEventRepository(nil))
+	eventService := services.NewEventService(memory.NewEventRepository(nil), productRepo, vectors, experiments)
+
+	version := scorer.CorpusVersion()
+	before, _ := vectors.Get(flashlight.ID.Hex())
+	cached, _ := vectors.Get(lantern.ID.Hex())
+
+	events := []*entities.Event{}
+	for range 20 {
+		events = append(events, &entities.Event{ProductID: flashlight.ID.Hex(), Type: entities.EventClick, SessionID: "s1"})
+	}
+	_, err := eventService.RecordEvents(events)
+	require.NoError(t, err)
+
+	assert.Equal(t, version, scorer.CorpusVersion(), "count changes shouldn't throw the weighted vectors away")
+
+	after, _ := vectors.Get(flashlight.ID.Hex())
+	assert.Greater(t, after["click_count"], before["click_count"], "the product's own ranks are refreshed right away")
+
+	unchanged, _ := vectors.Get(lantern.ID.Hex())
+	assert.Equal(t, cached["click_count"], unchanged["click_count"], "the other products keep their ranks until the next refresh")
+
+	persisted, err := vectorRepo.GetAll()
+	require.NoError(t, err)
+
+	for _, vector := range persisted {
+		if vector.ProductID == flashlight.ID {
+			assert.Equal(t, 21, vector.ClickCount)
+		}
+	}
+}
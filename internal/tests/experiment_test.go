@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testExperiment = services.ExperimentConfig{
+	Name: "hybrid-vs-content",
+	Arms: []services.ExperimentArm{
+		{Name: "control", Weight: 75, Strategy: services.StrategyContent},
+		{Name: "hybrid", Weight: 25, Strategy: services.StrategyHybrid, Diversify: true, Lambda: 0.5},
+	},
+}
+
+func TestExperimentConfig_Validate(t *testing.T) {
+	require.NoError(t, testExperiment.Validate())
+	require.NoError(t, services.DefaultExperimentConfig().Validate(), "no arms means no experiment")
+
+	for _, arms := range [][]services.ExperimentArm{
+		{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}},
+		{{Name: "a", Weight: 0}},
+		{{Name: "a", Weight: 1, Strategy: "random"}},
+		{{Name: "a", Weight: 1, Lambda: 2}},
+	} {
+		assert.Error(t, services.ExperimentConfig{Name: "test", Arms: arms}.Validate(), "expected an error for %+v", arms)
+	}
+
+	assert.Error(t, services.ExperimentConfig{Arms: []services.ExperimentArm{{Name: "a", Weight: 1}}}.Validate())
+}
+
+func TestExperimentService_Assign(t *testing.T) {
+	experiments := services.NewExperimentService(testExperiment, memory.NewImpressionRepository(), memory.NewEventRepository(nil))
+
+	counts := map[string]int{}
+
+	for i := 0; i < 2000; i++ {
+		unit := services.ExperimentUnit(fmt.Sprintf("session-%d", i), "")
+
+		arm, ok := experiments.Assign(unit)
+		require.True(t, ok)
+
+		again, _ := experiments.Assign(unit)
+		require.Equal(t, arm.Name, again.Name, "a unit always lands in the same arm")
+
+		counts[arm.Name]++
+	}
+
+	assert.InDelta(t, 1500, counts["control"], 100, "arms get traffic by their weight")
+	assert.InDelta(t, 500, counts["hybrid"], 100)
+
+	_, ok := experiments.Assign("")
+	assert.False(t, ok, "requests without a session or user aren't bucketed")
+
+	_, ok = services.NewExperimentService(services.DefaultExperimentConfig(), memory.NewImpressionRepository(), memory.NewEventRepository(nil)).Assign("session:s1")
+	assert.False(t, ok, "nothing is bucketed without an experiment")
+
+	assert.Equal(t, services.ExperimentUnit("s1", "u1"), services.ExperimentUnit("s2", "u1"), "users keep their arm across sessions")
+}
+
+func TestExperimentService_ApplyAndStamp(t *testing.T) {
+	experiments := services.NewExperimentService(testExperiment, memory.NewImpressionRepository(), memory.NewEventRepository(nil))
+
+	// Find a session in each arm
+	sessions := map[string]string{}
+
+	for i := 0; len(sessions) < 2; i++ {
+		session := fmt.Sprintf("session-%d", i)
+		arm, _ := experiments.Assign(services.ExperimentUnit(session, ""))
+		sessions[arm.Name] = session
+	}
+
+	opts, assignment := experiments.Apply(services.ExperimentUnit(sessions["hybrid"], ""), services.DefaultRecommendationOptions())
+	require.NotNil(t, assignment)
+	assert.Equal(t, services.ExperimentAssignment{Experiment: "hybrid-vs-content", Arm: "hybrid"}, *assignment)
+	assert.Equal(t, services.StrategyHybrid, opts.Strategy)
+	assert.True(t, opts.Diversify)
+	assert.Equal(t, 0.5, opts.Lambda)
+
+	opts, assignment = experiments.Apply("", services.DefaultRecommendationOptions())
+	assert.Nil(t, assignment)
+	assert.Equal(t, services.DefaultRecommendationOptions().Strategy, opts.Strategy)
+
+	events := []*entities.Event{
+		{ProductID: "p1", Type: entities.EventClick, SessionID: sessions["control"], Arm: "hybrid"},
+		{ProductID: "p1", Type: entities.EventClick, Experiment: "spoofed", Arm: "hybrid"},
+	}
+
+	experiments.Stamp(events)
+
+	assert.Equal(t, "hybrid-vs-content", events[0].Experiment)
+	assert.Equal(t, "control", events[0].Arm, "arms sent by clients are overwritten")
+	assert.Empty(t, events[1].Experiment)
+	assert.Empty(t, events[1].Arm)
+}
+
+func TestExperimentService_Report(t *testing.T) {
+	events := memory.NewEventRepository(nil)
+	experiments := services.NewExperimentService(testExperiment, memory.NewImpressionRepository(), events)
+
+	sessions := map[string]string{}
+
+	for i := 0; len(sessions) < 2; i++ {
+		session := fmt.Sprintf("session-%d", i)
+		arm, _ := experiments.Assign(services.ExperimentUnit(session, ""))
+		sessions[arm.Name] = session
+	}
+
+	recommendation := func() (*services.Recommendation, string) {
+		id := primitive.NewObjectID()
+		return &services.Recommendation{Product: &entities.Product{ID: id}}, id.Hex()
+	}
+
+	lantern, lanternID := recommendation()
+	tent, tentID := recommendation()
+
+	clickedEarly := []*entities.Event{
+		{ProductID: lanternID, Type: entities.EventClick, SessionID: sessions["control"], Timestamp: time.Now().Add(-time.Hour)},
+	}
+	experiments.Stamp(clickedEarly)
+	_, err := events.InsertMany(clickedEarly)
+	require.NoError(t, err)
+
+	for arm, session := range sessions {
+		unit := services.ExperimentUnit(session, "")
+		_, assignment := experiments.Apply(unit, services.DefaultRecommendationOptions())
+		require.Equal(t, arm, assignment.Arm)
+
+		result := &services.RecommendationResult{Recommendations: []*services.Recommendation{lantern, tent}}
+		experiments.RecordImpression(unit, assignment, "seed", result)
+		experiments.RecordImpression(unit, assignment, "seed", result)
+	}
+
+	clicks := []*entities.Event{
+		{ProductID: lanternID, Type: entities.EventClick, SessionID: sessions["hybrid"], Timestamp: time.Now().Add(time.Second)},
+		{ProductID: lanternID, Type: entities.EventClick, SessionID: sessions["hybrid"], Timestamp: time.Now().Add(time.Second)},
+		{ProductID: tentID, Type: entities.EventClick, SessionID: sessions["hybrid"], Timestamp: time.Now().Add(time.Second)},
+		{ProductID: primitive.NewObjectID().Hex(), Type: entities.EventClick, SessionID: sessions["control"], Timestamp: time.Now().Add(time.Second)},
+	}
+	experiments.Stamp(clicks)
+	_, err = events.InsertMany(clicks)
+	require.NoError(t, err)
+
+	report, err := experiments.Report("")
+	require.NoError(t, err)
+	assert.Equal(t, "hybrid-vs-content", report.Experiment)
+	assert.Equal(t, []*services.ArmReport{
+		{Arm: "control", Impressions: 2, Clicks: 0, CTR: 0},
+		{Arm: "hybrid", Impressions: 2, Clicks: 2, CTR: 1},
+	}, report.Arms, "clicks before the recommendations were served, or on products that weren't, don't count")
+}
@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/adapters/web/handlers"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMerchandisingRules(t *testing.T) {
+	flashlight := newTestProduct("Linterna táctica", "LINTERNAS")
+	headlamp := newTestProduct("Linterna frontal", "LINTERNAS")
+	lantern := newTestProduct("Linterna farol", "LINTERNAS")
+	discontinued := newTestProduct("Linterna de mano", "LINTERNAS")
+	promoted := newTestProduct("Carpa iglú", "CAMPING")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, lantern, discontinued, promoted})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	scorer.SetCategoryTree(services.NewCategoryTree([]*entities.Category{
+		{ID: primitive.NewObjectID(), Name: "ACCESORIOS", Subcategories: []string{"LINTERNAS"}},
+	}))
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+
+	merchandising := services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer)
+	cache := services.NewRecommendationCache(services.DefaultCacheConfig())
+	merchandising.Watch(cache.Clear)
+
+	productService := services.NewCachedProductService(services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	}, services.NewBrainService(15), merchandising), cache)
+
+	opts := services.DefaultRecommendationOptions()
+
+	result, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 3)
+	assert.Empty(t, result.Merchandising)
+
+	rules := []*entities.MerchandisingRule{
+		{Name: "promote tents", Action: entities.MerchandisingPin, Conditions: entities.MerchandisingConditions{Categories: []string{"accesorios"}},
+			Target: entities.MerchandisingTarget{ProductIDs: []string{promoted.ID.Hex(), discontinued.ID.Hex()}}},
+		{Name: "bury discontinued", Action: entities.MerchandisingExclude, Target: entities.MerchandisingTarget{ProductIDs: []string{discontinued.ID.Hex()}}},
+		{Name: "boost lanterns", Action: entities.MerchandisingBoost, Factor: 10, Target: entities.MerchandisingTarget{ProductIDs: []string{lantern.ID.Hex()}}},
+		{Name: "home only", Action: entities.MerchandisingExclude, Conditions: entities.MerchandisingConditions{Categories: []string{"HOGAR"}},
+			Target: entities.MerchandisingTarget{Categories: []string{"LINTERNAS"}}},
+	}
+
+	for _, rule := range rules {
+		require.NoError(t, merchandising.CreateRule(rule))
+	}
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+
+	ids := []primitive.ObjectID{}
+	for _, recommendation := range result.Recommendations {
+		ids = append(ids, recommendation.Product.ID)
+	}
+
+	assert.Equal(t, []primitive.ObjectID{promoted.ID, lantern.ID, headlamp.ID}, ids,
+		"pinned products are added at their slot, boosted ones move up and excluded ones can't be pinned back")
+	assert.Equal(t, []*entities.MerchandisingRuleHit{
+		{RuleID: rules[1].ID.Hex(), Name: "bury discontinued", Action: entities.MerchandisingExclude, Products: []string{discontinued.ID.Hex()}},
+		{RuleID: rules[2].ID.Hex(), Name: "boost lanterns", Action: entities.MerchandisingBoost, Products: []string{lantern.ID.Hex()}},
+		{RuleID: rules[0].ID.Hex(), Name: "promote tents", Action: entities.MerchandisingPin, Products: []string{promoted.ID.Hex()}},
+	}, result.Merchandising, "rules that didn't apply aren't reported")
+	assert.Equal(t, int64(1), cache.Stats().Invalidations, "changing the rules drops the cached recommendations")
+
+	// Pins keep their slot within the page
+	page := opts
+	page.Limit = 1
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), page)
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 1)
+	assert.Equal(t, promoted.ID, result.Recommendations[0].Product.ID)
+
+	// Products outside the conditions aren't affected
+	result, err = productService.GetRecommendations(promoted.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Merchandising)
+
+	require.NoError(t, merchandising.DeleteRule(rules[0].ID.Hex()))
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 2)
+	assert.Equal(t, lantern.ID, result.Recommendations[0].Product.ID)
+}
+
+func TestMerchandisingRules_Brands(t *testing.T) {
+	product := func(name, brand string) *entities.Product {
+		product := newTestProduct(name, "LINTERNAS")
+		product.Brand = brand
+		return product
+	}
+
+	flashlight, headlamp, lantern, torch := product("Linterna táctica", "Lumex"), product("Linterna frontal", "Petzl"),
+		product("Linterna farol", "lumex"), product("Linterna de mano", "")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, lantern, torch})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	merchandising := services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer)
+
+	productService := services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	}, services.NewBrainService(15), merchandising)
+
+	boost := &entities.MerchandisingRule{Name: "boost petzl", Action: entities.MerchandisingBoost, Factor: 10,
+		Target: entities.MerchandisingTarget{Brands: []string{"PETZL"}}}
+	exclusion := &entities.MerchandisingRule{Name: "bury lumex", Action: entities.MerchandisingExclude,
+		Target: entities.MerchandisingTarget{Brands: []string{"LUMEX"}}}
+	require.NoError(t, merchandising.CreateRule(boost))
+	require.NoError(t, merchandising.CreateRule(exclusion))
+
+	result, err := productService.GetRecommendations(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+
+	ids := []primitive.ObjectID{}
+	for _, recommendation := range result.Recommendations {
+		ids = append(ids, recommendation.Product.ID)
+	}
+
+	assert.Equal(t, []primitive.ObjectID{headlamp.ID, torch.ID}, ids, "brands are matched ignoring case, and products without one are left alone")
+	assert.Equal(t, []*entities.MerchandisingRuleHit{
+		{RuleID: exclusion.ID.Hex(), Name: "bury lumex", Action: entities.MerchandisingExclude, Products: []string{lantern.ID.Hex()}},
+		{RuleID: boost.ID.Hex(), Name: "boost petzl", Action: entities.MerchandisingBoost, Products: []string{headlamp.ID.Hex()}},
+	}, result.Merchandising)
+}
+
+// hydrationCountingRepository records how many products each GetByIDs call of the wrapped repository loads
+type hydrationCountingRepository struct {
+	repositories.ProductRepository
+	loaded []int
+}
+
+func (r *hydrationCountingRepository) GetByIDs(ids []string, query repositories.ProductQuery) ([]*entities.Product, error) {
+	r.loaded = append(r.loaded, len(ids))
+	return r.ProductRepository.GetByIDs(ids, query)
+}
+
+func TestMerchandisingRules_LoadsOnlyThePage(t *testing.T) {
+	flashlight := newTestProduct("Linterna táctica", "LINTERNAS")
+	catalog := []*entities.Product{flashlight}
+	for _, name := range []string{"Linterna frontal", "Linterna farol", "Linterna de mano", "Linterna de bolsillo", "Linterna solar"} {
+		catalog = append(catalog, newTestProduct(name, "LINTERNAS"))
+	}
+	tent := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"CAMPING"}, Published: true}
+	catalog = append(catalog, tent)
+
+	productRepo := &hydrationCountingRepository{ProductRepository: memory.NewProductRepository(catalog)}
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	merchandising := services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer)
+
+	productService := services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	}, services.NewBrainService(15), merchandising)
+
+	require.NoError(t, merchandising.CreateRule(&entities.MerchandisingRule{Name: "boost solar", Action: entities.MerchandisingBoost, Factor: 10,
+		Target: entities.MerchandisingTarget{ProductIDs: []string{catalog[5].ID.Hex()}}}))
+	require.NoError(t, merchandising.CreateRule(&entities.MerchandisingRule{Name: "promote tents", Action: entities.MerchandisingPin, Position: 2,
+		Target: entities.MerchandisingTarget{ProductIDs: []string{tent.ID.Hex()}}}))
+	exclusion := &entities.MerchandisingRule{Name: "bury flashlights", Action: entities.MerchandisingExclude, Target: entities.MerchandisingTarget{Categories: []string{"linternas"}}}
+	require.NoError(t, merchandising.CreateRule(exclusion))
+
+	// Excluding every flashlight leaves the pinned tent alone
+	result, err := productService.GetRecommendations(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 1)
+	assert.Equal(t, tent.ID, result.Recommendations[0].Product.ID)
+
+	require.NoError(t, merchandising.DeleteRule(exclusion.ID.Hex()))
+
+	productRepo.loaded = nil
+
+	opts := services.DefaultRecommendationOptions()
+	opts.Limit = 2
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+
+	ids := []primitive.ObjectID{}
+	for _, recommendation := range result.Recommendations {
+		ids = append(ids, recommendation.Product.ID)
+	}
+
+	assert.Equal(t, []primitive.ObjectID{catalog[5].ID, tent.ID}, ids, "the boosted product leads and the pin keeps its slot")
+	assert.Equal(t, []int{1, 2}, productRepo.loaded, "the product itself, then only the products of the page are loaded")
+}
+
+func TestMerchandisingRuleRoutes(t *testing.T) {
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	merchandising := services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(),
+		services.NewVectorStore(memory.NewProductRepository(nil), memory.NewProductVectorRepository(), scorer), scorer)
+	handler := handlers.NewMerchandisingRuleHandler(merchandising)
+
+	router := gin.Default()
+	router.POST("/merchandising-rules", handler.CreateRule)
+	router.GET("/merchandising-rules", handler.GetAllRules)
+	router.GET("/merchandising-rules/:id", handler.GetRuleByID)
+	router.PUT("/merchandising-rules/:id", handler.UpdateRule)
+	router.DELETE("/merchandising-rules/:id", handler.DeleteRule)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	validID := primitive.NewObjectID().Hex()
+
+	for _, body := range []string{
+		`{"action": "exclude", "target": {"categories": ["LINTERNAS"]}}`,
+		`{"name": "rule", "action": "hide", "target": {"categories": ["LINTERNAS"]}}`,
+		`{"name": "rule", "action": "boost", "target": {"categories": ["LINTERNAS"]}}`,
+		`{"name": "rule", "action": "pin", "target": {"categories": ["LINTERNAS"]}}`,
+		`{"name": "rule", "action": "pin", "target": {"brands": ["Petzl"]}}`,
+		`{"name": "rule", "action": "pin", "target": {"productIds": ["not-an-id"]}}`,
+		`{"name": "rule", "action": "exclude"}`,
+	} {
+		resp := request("POST", "/merchandising-rules", body)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "expected status code 400 for %s", body)
+		assert.Regexp(t, "error|validationErrors", resp.Body.String())
+	}
+
+	resp := request("POST", "/merchandising-rules", `{"name": "promote", "priority": 10, "action": "pin", "position": 2, "target": {"productIds": ["`+validID+`"]}}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var created entities.MerchandisingRule
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.False(t, created.ID.IsZero())
+	assert.Equal(t, 2, created.Position)
+
+	resp = request("POST", "/merchandising-rules", `{"name": "bury", "action": "boost", "factor": 0.1, "target": {"categories": ["LINTERNAS"]}}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = request("GET", "/merchandising-rules", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var listed []*entities.MerchandisingRule
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Equal(t, "promote", listed[0].Name, "rules are listed by priority")
+
+	resp = request("PUT", "/merchandising-rules/"+created.ID.Hex(), `{"name": "promote", "action": "pin", "target": {"productIds": ["`+validID+`"]}}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = request("GET", "/merchandising-rules/"+created.ID.Hex(), "")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var updated entities.MerchandisingRule
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &updated))
+	assert.Zero(t, updated.Position, "updates replace the whole rule")
+	assert.Equal(t, created.CreatedAt.Unix(), updated.CreatedAt.Unix())
+
+	resp = request("PUT", "/merchandising-rules/"+primitive.NewObjectID().Hex(), `{"name": "promote", "action": "pin", "target": {"productIds": ["`+validID+`"]}}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = request("DELETE", "/merchandising-rules/"+created.ID.Hex(), "")
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = request("GET", "/merchandising-rules/"+created.ID.Hex(), "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
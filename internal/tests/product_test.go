@@ -4,6 +4,7 @@ import (
 	"backend-challenge/internal/adapters/persistence/repository"
 	"backend-challenge/internal/application/services"
 	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -197,6 +198,7 @@ func TestProductUpdate(t *testing.T) {
 	assert.Equal(t, updateProduct.Name.LocalizedString.En, updatedProduct.Name.LocalizedString.En)
 	assert.Equal(t, updateProduct.Description.LocalizedString.En, updatedProduct.Description.LocalizedString.En)
 	assert.Equal(t, updateProduct.SoldCount, updatedProduct.SoldCount)
+	assert.False(t, updatedProduct.Published, "published is written even when false")
 }
 
 func TestGetRecommendations(t *testing.T) {
@@ -206,14 +208,20 @@ func TestGetRecommendations(t *testing.T) {
 
 	productRepo := GetProductRepo()
 
-	recommendationService := services.NewRecommendationService()
+	productVectorRepo := repository.NewProductVectorRepository(testClient, "backend-challenge-test", "product_vectors")
 
-	productService := services.NewProductService(productRepo, recommendationService)
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	vectorStore := services.NewVectorStore(productRepo, productVectorRepo, recommendationService)
+
+	productService := services.NewProductService(productRepo, itemSimilarityRepo, vectorStore, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectorStore, recommendationService),
+	}, services.NewBrainService(15), services.NewMerchandisingService(repository.NewMerchandisingRuleRepository(testClient, "backend-challenge-test", "merchandising_rules"), vectorStore, recommendationService))
 
 	// Mock data
-	productA := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}}
-	productB := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}}
-	productC := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Home Appliances"}}
+	productA := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}, Published: true}
+	productB := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}, Published: true}
+	productC := &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Home Appliances"}, Published: true}
 
 	err := productRepo.Create(productA)
 	assert.NoError(t, err)
@@ -230,16 +238,16 @@ func TestGetRecommendations(t *testing.T) {
 
 	assert.Equal(t, productA, product)
 
-	allResults, allResultsErr := productRepo.GetAll()
+	allResults, allResultsErr := productRepo.GetAll(repositories.ProductQuery{})
 
 	assert.NoError(t, allResultsErr)
 
 	assert.Equal(t, []*entities.Product{productA, productB, productC}, allResults)
 
-	recommendations, err := productService.GetRecommendations(productA.ID.Hex())
+	result, err := productService.GetRecommendations(productA.ID.Hex(), services.DefaultRecommendationOptions())
 
 	assert.NoError(t, err)
-	assert.Equal(t, recommendations[0].Product.ID, productB.ID)
+	assert.Equal(t, result.Recommendations[0].Product.ID, productB.ID)
 
 }
 
@@ -312,4 +320,63 @@ func TestCreateProductRoute(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, initialProduct.Name.LocalizedString.En, fetchedProduct.Name.LocalizedString.En)
 	
-}
\ No newline at end of file
+}
+func TestVectorStoreIncrementalUpdates(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	productRepo := GetProductRepo()
+	productVectorRepo := repository.NewProductVectorRepository(testClient, "backend-challenge-test", "product_vectors")
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectorStore := services.NewVectorStore(productRepo, productVectorRepo, recommendationService)
+	productService := services.NewProductService(productRepo, itemSimilarityRepo, vectorStore, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectorStore, recommendationService),
+	}, services.NewBrainService(15), services.NewMerchandisingService(repository.NewMerchandisingRuleRepository(testClient, "backend-challenge-test", "merchandising_rules"), vectorStore, recommendationService))
+
+	require.NoError(t, vectorStore.Load())
+
+	product := &entities.Product{
+		Categories: []string{"Electronics"},
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Laptop")}},
+	}
+
+	require.NoError(t, productService.CreateProduct(product))
+
+	vector, ok := vectorStore.Get(product.ID.Hex())
+	assert.True(t, ok, "expected a vector for the created product")
+	assert.Contains(t, vector, "category_electronics")
+
+	persisted, err := productVectorRepo.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, persisted, 1, "expected the vector to be persisted")
+
+	err = productService.UpdateProduct(&entities.Product{ID: product.ID, Categories: []string{"Computers"}})
+	require.NoError(t, err)
+
+	vector, _ = vectorStore.Get(product.ID.Hex())
+	assert.Contains(t, vector, "category_computers")
+	assert.NotContains(t, vector, "category_electronics")
+
+	require.NoError(t, productService.DeleteProduct(product.ID.Hex()))
+
+	_, ok = vectorStore.Get(product.ID.Hex())
+	assert.False(t, ok, "expected the vector to be removed with the product")
+}
+
+func TestGetRecommendationsRoute_InvalidOptions(t *testing.T) {
+	router := gin.Default()
+
+	productHandler := GetProductHandler()
+
+	router.GET("/products/:id/recommendations", productHandler.GetRecommendations)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=500", "offset=-1", "minScore=2", "minScore=abc", "diversify=maybe", "lambda=1.5", "maxPerCategory=0", "outOfStockPenalty=2", "includeUnpublished=maybe", "priceLimit=abc"} {
+		req, _ := http.NewRequest("GET", "/products/"+primitive.NewObjectID().Hex()+"/recommendations?"+query, nil)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "expected status code 400 for %s", query)
+		assert.Contains(t, resp.Body.String(), "error")
+	}
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCachedProductService(t *testing.T) {
+	flashlight, headlamp, tent, bigTent := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Linterna frontal", "Linternas"), newTestProduct("Carpa iglú", "Carpas"), newTestProduct("Carpa familiar", "Carpas")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, tent, bigTent})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+
+	cache := services.NewRecommendationCache(services.CacheConfig{Size: 2})
+	productService := services.NewCachedProductService(services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	}, services.NewBrainService(15), services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer)), cache)
+
+	opts := services.DefaultRecommendationOptions()
+
+	result, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 1)
+	assert.Equal(t, headlamp.ID, result.Recommendations[0].Product.ID)
+
+	// Annotating a result doesn't change the cached one
+	result.Experiment = &services.ExperimentAssignment{Experiment: "test", Arm: "control"}
+
+	cached, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, result.Recommendations, cached.Recommendations)
+	assert.Nil(t, cached.Experiment)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRatio)
+	assert.Equal(t, 1, stats.Size)
+
+	// Other options are cached apart
+	explained := opts
+	explained.Explain = true
+
+	_, err = productService.GetRecommendations(flashlight.ID.Hex(), explained)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cache.Stats().Misses)
+
+	_, err = productService.GetRecommendations(tent.ID.Hex(), opts)
+	require.NoError(t, err)
+
+	stats = cache.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, int64(1), stats.Evictions, "the least recently used result makes room")
+
+	// Writing a product drops only the results it appears in
+	headlamp.Name.Es = ptr("Linterna frontal recargable")
+	require.NoError(t, productService.UpdateProduct(headlamp))
+
+	stats = cache.Stats()
+	assert.Equal(t, int64(1), stats.Invalidations)
+	assert.Equal(t, 1, stats.Size)
+
+	_, err = productService.GetRecommendations(tent.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cache.Stats().Hits, "the tent's recommendations don't include the headlamp")
+
+	require.NoError(t, productService.DeleteProduct(bigTent.ID.Hex()))
+
+	result, err = productService.GetRecommendations(tent.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Recommendations, "deleted products leave the cache")
+	assert.Equal(t, int64(2), cache.Stats().Invalidations)
+}
+
+func TestRecommendationCache_TTL(t *testing.T) {
+	cache := services.NewRecommendationCache(services.CacheConfig{Size: 10, TTLSeconds: 1})
+	productID := primitive.NewObjectID().Hex()
+
+	cache.Put("key", productID, &services.RecommendationResult{Recommendations: []*services.Recommendation{}})
+
+	_, ok := cache.Get("key")
+	assert.True(t, ok)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	_, ok = cache.Get("key")
+	assert.False(t, ok, "results expire after the TTL")
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Expirations)
+	assert.Equal(t, 0, stats.Size)
+
+	disabled := services.NewRecommendationCache(services.CacheConfig{})
+	disabled.Put("key", productID, &services.RecommendationResult{})
+
+	_, ok = disabled.Get("key")
+	assert.False(t, ok, "a zero size disables the cache")
+}
+
+func TestRecommendationCache_Staleness(t *testing.T) {
+	flashlight, headlamp, tent := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Vincha luminosa", "Frontales"), newTestProduct("Carpa iglú", "Carpas")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, tent})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+
+	cache := services.NewRecommendationCache(services.CacheConfig{Size: 10, TTLSeconds: 300, PopularityTTLSeconds: 1})
+	productService := services.NewCachedProductService(services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+		services.StrategyHybrid: services.NewHybridRecommender(vectors, scorer, memory.NewItemSimilarityRepository(),
+			services.NewPopularityService(memory.NewEventRepository(nil), services.DefaultPopularityConfig()), services.DefaultHybridConfig()),
+	}, services.NewBrainService(15), services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer)), cache)
+
+	categoryService := services.NewCategoryService(memory.NewCategoryRepository(nil), scorer)
+	categoryService.Watch(cache.Clear)
+
+	opts := services.DefaultRecommendationOptions()
+
+	result, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Recommendations, "flashlights and headlamps are unrelated until the hierarchy says otherwise")
+
+	require.NoError(t, categoryService.CreateCategory(&entities.Category{Name: "LINTERNAS", Subcategories: []string{"frontales"}}))
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 1, "category writes drop the cached results")
+	assert.Equal(t, headlamp.ID, result.Recommendations[0].Product.ID)
+
+	// Results scoring popularity expire sooner, since ingested events don't invalidate them
+	hybrid := opts
+	hybrid.Strategy = services.StrategyHybrid
+
+	for _, options := range []services.RecommendationOptions{opts, hybrid} {
+		_, err = productService.GetRecommendations(flashlight.ID.Hex(), options)
+		require.NoError(t, err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	expirations := cache.Stats().Expirations
+
+	for _, options := range []services.RecommendationOptions{opts, hybrid} {
+		_, err = productService.GetRecommendations(flashlight.ID.Hex(), options)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, expirations+2, cache.Stats().Expirations, "content results score click and sold counts too")
+
+	// Callers get their own copy of the cached result
+	result.Recommendations[0].SimilarityScore = -1
+	result.Recommendations[0] = nil
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 1)
+	result.Recommendations[0].SimilarityScore = -1
+
+	cached, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	require.NotNil(t, cached.Recommendations[0])
+	assert.Positive(t, cached.Recommendations[0].SimilarityScore)
+}
@@ -1,10 +1,15 @@
 package tests
 
 import (
+	"backend-challenge/internal/application/services"
 	"backend-challenge/internal/domain/entities"
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -31,15 +36,17 @@ func TestRecommendSimilarProducts(t *testing.T) {
 		Categories:  []string{"Electronics"},
 		ClickCount: 80,
 		SoldCount: 40,
+		Published:   true,
 		Name:        entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Laptop B")}},
 		Description: entities.Description{LocalizedString: entities.LocalizedString{En: ptr("A compact laptop.")}},
 	}
 
 	productC := entities.Product{
 		ID:          primitive.NewObjectID(),
-		Categories:  []string{"Home Appliances"},
+		Categories:  []string{"Home Appliances", "Electronics"}, // candidates sharing nothing with the target aren't recommended
 		ClickCount:  20,
 		SoldCount:   10,
+		Published:   true,
 		Name:        entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Vacuum Cleaner")}},
 		Description: entities.Description{LocalizedString: entities.LocalizedString{En: ptr("A high-efficiency vacuum cleaner.")}},
 	}
@@ -47,14 +54,344 @@ func TestRecommendSimilarProducts(t *testing.T) {
 	allProducts := []*entities.Product{&productA, &productB, &productC}
 
 	// Get recommendations
-	recommendations := recommendationService.RecommendSimilarProducts(productA, allProducts)
+	recommendations := recommendationService.RecommendSimilarProducts(productA, allProducts, services.DefaultRecommendationOptions())
 
 	// Assert results
-	assert.Equal(t, 2, len(recommendations))
+	require.Equal(t, 2, len(recommendations))
 	assert.Equal(t, productB.ID, recommendations[0].Product.ID)
 	assert.True(t, recommendations[0].SimilarityScore > recommendations[1].SimilarityScore)
 }
 
+func TestRecommendSimilarProducts_Options(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	target := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}}
+
+	allProducts := []*entities.Product{&target}
+	for i := 0; i < 4; i++ {
+		allProducts = append(allProducts, &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}, Published: true})
+	}
+	// shares nothing with the target, so it scores zero
+	allProducts = append(allProducts, &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Garden"}, Published: true})
+
+	all := recommendationService.RecommendSimilarProducts(target, allProducts, services.RecommendationOptions{Limit: 10})
+	assert.Equal(t, 4, len(all), "zero score products should never be returned")
+
+	page := recommendationService.RecommendSimilarProducts(target, allProducts, services.RecommendationOptions{Limit: 2, Offset: 1})
+	assert.Equal(t, 2, len(page))
+	assert.Equal(t, all[1].Product.ID, page[0].Product.ID)
+
+	none := recommendationService.RecommendSimilarProducts(target, allProducts, services.RecommendationOptions{Limit: 10, Offset: 10})
+	assert.Empty(t, none)
+}
+
+func TestRecommendSimilarProducts_NothingShared(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	product := func(name, category string, price float64, sold int) *entities.Product {
+		product := newTestProduct(name, category)
+		product.Variants = []entities.Variant{{Value: "Unico", Stock: 1, Price: price}}
+		product.SoldCount = sold
+		return product
+	}
+
+	flashlight := product("Linterna táctica", "Linternas", 20, 10)
+	otherFlashlight := product("Linterna frontal", "Linternas", 500, 1000)
+	pan := product("Sartén antiadherente", "Cocina", 20, 10)
+	hose := product("Manguera extensible", "Jardin", 25, 12)
+
+	allProducts := []*entities.Product{flashlight, otherFlashlight, pan, hose}
+	recommendationService.BuildCorpus(allProducts)
+
+	recommendations := recommendationService.RecommendSimilarProducts(*flashlight, allProducts, services.RecommendationOptions{Limit: 10})
+	require.Len(t, recommendations, 1, "sharing only a price band or popularity isn't similarity")
+	assert.Equal(t, otherFlashlight.ID, recommendations[0].Product.ID)
+}
+
+func TestRecommendSimilarProducts_Explain(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	target := entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Linternas"},
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Linterna recargable")}},
+	}
+	similar := entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Linternas"},
+		Published:  true,
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Linterna frontal")}},
+	}
+
+	recommendations := recommendationService.RecommendSimilarProducts(target, []*entities.Product{&target, &similar}, services.RecommendationOptions{Limit: 5, Explain: true})
+
+	assert.Equal(t, 1, len(recommendations))
+
+	explanation := recommendations[0].Explanation
+	assert.NotEmpty(t, explanation)
+
+	var total float64
+	groups := map[string]bool{}
+	for _, contribution := range explanation {
+		total += contribution.Contribution
+		groups[contribution.Group] = true
+	}
+
+	assert.True(t, groups["category"], "expected the shared category to be explained")
+	assert.True(t, groups["name"], "expected the shared name word to be explained")
+	assert.InDelta(t, recommendations[0].SimilarityScore, total, 1e-9, "contributions should add up to the score")
+}
+
+func TestRecommendSimilarProducts_GroupWeights(t *testing.T) {
+	target := entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Linternas"},
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Linterna táctica")}},
+	}
+	sameCategory := entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Linternas"},
+		Published:  true,
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Farol frontal")}},
+	}
+	sameWords := entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: []string{"Repuestos"},
+		Published:  true,
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Linterna táctica repuesto")}},
+	}
+	allProducts := []*entities.Product{&target, &sameCategory, &sameWords}
+
+	defaults := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	recommendations := defaults.RecommendSimilarProducts(target, allProducts, services.DefaultRecommendationOptions())
+	assert.Equal(t, sameCategory.ID, recommendations[0].Product.ID, "by default a shared category should matter more than shared words")
+
+	config := services.DefaultRecommendationConfig()
+	config.Weights = map[string]float64{services.GroupCategory: 0.5, services.GroupName: 5}
+
+	tuned := services.NewRecommendationService(config)
+	recommendations = tuned.RecommendSimilarProducts(target, allProducts, services.DefaultRecommendationOptions())
+	assert.Equal(t, sameWords.ID, recommendations[0].Product.ID, "configured weights should let shared words win")
+}
+
+func TestExtractFeatureVector_PriceBandsRelativeToCategory(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	product := func(category string, price float64) *entities.Product {
+		product := newTestProduct("", category)
+		product.Variants = []entities.Variant{{Value: "Unico", Stock: 1, Price: price}}
+		return product
+	}
+
+	catalog := []*entities.Product{
+		product("Televisores", 1800), product("Televisores", 2000), product("Televisores", 2200),
+		product("Linternas", 18), product("Linternas", 20), product("Linternas", 22),
+	}
+
+	recommendationService.BuildCorpus(catalog)
+
+	television := recommendationService.ExtractFeatureVector(*catalog[1])
+	flashlight := recommendationService.ExtractFeatureVector(*catalog[4])
+	expensiveFlashlight := recommendationService.ExtractFeatureVector(*product("Linternas", 200))
+
+	// A typically priced product sits in the middle band of its category, whatever the absolute price
+	assert.Equal(t, 1.0, television["price_band_0"])
+	assert.Equal(t, 1.0, flashlight["price_band_0"])
+	assert.Zero(t, expensiveFlashlight["price_band_0"], "a flashlight ten times the usual price should be in a higher band")
+}
+
+func TestExtractFeatureVector_PopularityPercentiles(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	// Without a catalog to compare against, counts are log scaled instead of saturating
+	few := recommendationService.ExtractFeatureVector(entities.Product{ID: primitive.NewObjectID(), SoldCount: 10})
+	many := recommendationService.ExtractFeatureVector(entities.Product{ID: primitive.NewObjectID(), SoldCount: 10000})
+	assert.Greater(t, many["sold_count"], few["sold_count"])
+	assert.Less(t, many["sold_count"], 1.0)
+
+	catalog := []*entities.Product{}
+	for _, sold := range []int{0, 10, 100, 1000, 10000} {
+		catalog = append(catalog, &entities.Product{ID: primitive.NewObjectID(), SoldCount: sold, ClickCount: sold * 2})
+	}
+
+	recommendationService.BuildCorpus(catalog)
+
+	few = recommendationService.ExtractFeatureVector(*catalog[1])
+	many = recommendationService.ExtractFeatureVector(*catalog[4])
+	assert.InDelta(t, 0.3, few["sold_count"], 1e-9, "10 sales beat one of five products, tying with itself")
+	assert.InDelta(t, 0.9, many["sold_count"], 1e-9)
+	assert.InDelta(t, 0.9, many["click_count"], 1e-9)
+
+	// Ranks follow the catalog as counts change
+	before := recommendationService.BuildProductVector(*catalog[1])
+	catalog[1].SoldCount = 20000
+	after := recommendationService.BuildProductVector(*catalog[1])
+
+	recommendationService.RemoveFromCorpus(before)
+	recommendationService.AddToCorpus(after)
+
+	assert.InDelta(t, 0.9, recommendationService.ExtractFeatureVector(*catalog[1])["sold_count"], 1e-9)
+	assert.InDelta(t, 0.7, recommendationService.ExtractFeatureVector(*catalog[4])["sold_count"], 1e-9)
+}
+
+func TestSimilarity_PopularityRankDistance(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	vector := func(clicks, sold float64) map[string]float64 {
+		return map[string]float64{"category_linternas": 1.0, "click_count": clicks, "sold_count": sold}
+	}
+
+	target := vector(0.1, 0.1)
+
+	same := recommendationService.Similarity(target, vector(0.1, 0.1))
+	close := recommendationService.Similarity(target, vector(0.2, 0.15))
+	distant := recommendationService.Similarity(target, vector(0.9, 0.9))
+
+	assert.InDelta(t, 1.0, same, 1e-9)
+	assert.Greater(t, close, distant, "close ranks should beat distant ones")
+	assert.Less(t, distant, same, "a best seller is not as popular as a product that barely sells, however proportional their counts")
+
+	// Closeness adds up in the explanation like any other group
+	var popularity float64
+	for _, contribution := range recommendationService.Explain(target, vector(0.9, 0.9)) {
+		if contribution.Group == services.GroupPopularity {
+			popularity += contribution.Contribution
+		}
+	}
+
+	weights := services.DefaultRecommendationConfig().Weights
+	assert.InDelta(t, weights[services.GroupPopularity]*0.2/(weights[services.GroupCategory]+weights[services.GroupPopularity]), popularity, 1e-9)
+}
+
+func TestExtractFeatureVector_VariantsAndAvailability(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	vector := recommendationService.ExtractFeatureVector(entities.Product{
+		ID: primitive.NewObjectID(),
+		Variants: []entities.Variant{
+			{Value: "Negro / XL", Stock: 0, Price: 10},
+			{Value: "Azul Marino, M", Stock: 3, Price: 10},
+		},
+	})
+
+	assert.Equal(t, 1.0, vector["variant_negro"])
+	assert.Equal(t, 1.0, vector["variant_xl"])
+	assert.Equal(t, 1.0, vector["variant_azul marino"])
+	assert.Equal(t, 1.0, vector["availability_in_stock"])
+	assert.NotContains(t, vector, "availability_out_of_stock")
+}
+
+func TestRecommendSimilarProducts_OutOfStockPenalty(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	product := func(stock int) *entities.Product {
+		product := newTestProduct("Linterna táctica", "Linternas")
+		product.Variants = []entities.Variant{{Value: "Negro", Stock: stock, Price: 20}}
+		return product
+	}
+
+	target, outOfStock, inStock := product(5), product(0), product(5)
+	inStock.Name.Es = ptr("Linterna")
+	allProducts := []*entities.Product{target, outOfStock, inStock}
+
+	recommendations := recommendationService.RecommendSimilarProducts(*target, allProducts, services.DefaultRecommendationOptions())
+	assert.Equal(t, outOfStock.ID, recommendations[0].Product.ID, "without a penalty the closer match should win")
+
+	opts := services.DefaultRecommendationOptions()
+	opts.OutOfStockPenalty = 0.5
+
+	recommendations = recommendationService.RecommendSimilarProducts(*target, allProducts, opts)
+	assert.Equal(t, inStock.ID, recommendations[0].Product.ID, "the penalty should demote the product without stock")
+
+	opts.OutOfStockPenalty = 1
+
+	recommendations = recommendationService.RecommendSimilarProducts(*target, allProducts, opts)
+	assert.Len(t, recommendations, 1, "a full penalty should drop products without stock")
+}
+
+func TestRecommendSimilarProducts_Diversify(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	target := newTestProduct("Linterna táctica recargable", "Linternas")
+	allProducts := []*entities.Product{
+		target,
+		newTestProduct("Linterna táctica recargable negra", "Linternas"),
+		newTestProduct("Linterna táctica recargable azul", "Linternas"),
+		newTestProduct("Linterna táctica recargable roja", "Linternas"),
+		newTestProduct("Farol recargable", "Linternas", "Camping"),
+		newTestProduct("Pilas recargables", "Baterias"),
+	}
+	nearDuplicates := map[primitive.ObjectID]bool{allProducts[1].ID: true, allProducts[2].ID: true, allProducts[3].ID: true}
+
+	opts := services.DefaultRecommendationOptions()
+	opts.Limit = 2
+
+	recommendations := recommendationService.RecommendSimilarProducts(*target, allProducts, opts)
+	assert.True(t, nearDuplicates[recommendations[0].Product.ID])
+	assert.True(t, nearDuplicates[recommendations[1].Product.ID], "by relevance alone the top is all near duplicates")
+
+	opts.Diversify = true
+	opts.Lambda = 0.3
+
+	recommendations = recommendationService.RecommendSimilarProducts(*target, allProducts, opts)
+	assert.True(t, nearDuplicates[recommendations[0].Product.ID], "the most relevant product is still picked first")
+	assert.False(t, nearDuplicates[recommendations[1].Product.ID], "a second near duplicate should give way to variety")
+
+	opts.Lambda = 1
+
+	recommendations = recommendationService.RecommendSimilarProducts(*target, allProducts, opts)
+	assert.True(t, nearDuplicates[recommendations[1].Product.ID], "a lambda of 1 only considers relevance")
+}
+
+func TestRecommendSimilarProducts_MaxPerCategory(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	target := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Linternas", "Camping"}}
+
+	allProducts := []*entities.Product{&target}
+	for i := 0; i < 3; i++ {
+		allProducts = append(allProducts, &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Linternas"}, Published: true})
+		allProducts = append(allProducts, &entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Camping"}, Published: true})
+	}
+
+	opts := services.RecommendationOptions{Limit: 10, MaxPerCategory: 2}
+
+	recommendations := recommendationService.RecommendSimilarProducts(target, allProducts, opts)
+	assert.Len(t, recommendations, 4)
+
+	perCategory := map[string]int{}
+	for _, recommendation := range recommendations {
+		perCategory[recommendation.Product.Categories[0]]++
+	}
+	assert.Equal(t, map[string]int{"Linternas": 2, "Camping": 2}, perCategory)
+}
+
+func TestRecommendSimilarProducts_MaxPerSubcategory(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	recommendationService.SetCategoryTree(services.NewCategoryTree([]*entities.Category{
+		{ID: primitive.NewObjectID(), Name: "ACCESORIOS", Subcategories: []string{"Linternas", "Faroles", "Cuchillos"}},
+	}))
+
+	target := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Linternas"}}
+
+	allProducts := []*entities.Product{&target}
+	for i := 0; i < 3; i++ {
+		for _, category := range []string{"Linternas", "Faroles", "Cuchillos"} {
+			allProducts = append(allProducts, &entities.Product{ID: primitive.NewObjectID(), Categories: []string{category}, Published: true})
+		}
+	}
+
+	opts := services.RecommendationOptions{Limit: 10, MaxPerCategory: 2}
+
+	recommendations := recommendationService.RecommendSimilarProducts(target, allProducts, opts)
+
+	perCategory := map[string]int{}
+	for _, recommendation := range recommendations {
+		perCategory[recommendation.Product.Categories[0]]++
+	}
+	assert.Equal(t, map[string]int{"Linternas": 2, "Faroles": 2, "Cuchillos": 2}, perCategory, "the shared parent doesn't count towards the limit")
+}
+
 func TestCosineSimilarity(t *testing.T) {
 	recommendationService := GetRecommendationService()
 
@@ -66,6 +403,142 @@ func TestCosineSimilarity(t *testing.T) {
 	assert.True(t, similarity > 0.8) // vec1 and vec2 overlap heavily so expect high similarity score
 }
 
+func TestExtractFeatureVector_IDFWeighting(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	catalog := []*entities.Product{
+		{ID: primitive.NewObjectID(), Name: entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Linterna led recargable")}}},
+		{ID: primitive.NewObjectID(), Name: entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Tira led")}}},
+		{ID: primitive.NewObjectID(), Name: entities.Name{LocalizedString: entities.LocalizedString{Es: ptr("Lampara led")}}},
+	}
+
+	recommendationService.BuildCorpus(catalog)
+
+	vector := recommendationService.ExtractFeatureVector(*catalog[0])
+
+	// "led" appears in every product, so it must weigh less than the rarer "linterna"
+	assert.True(t, recommendationService.HasCorpus())
+	assert.Greater(t, vector["name_lintern"], vector["name_led"])
+}
+
+func TestInvertedIndexCandidates(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	index := services.NewInvertedIndex()
+
+	laptop := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}, Name: entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Laptop")}}}
+	tablet := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Electronics"}, Name: entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Tablet")}}}
+	bag := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Accessories"}, Name: entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Laptop bag")}}}
+	vacuum := entities.Product{ID: primitive.NewObjectID(), Categories: []string{"Home Appliances"}, Name: entities.Name{LocalizedString: entities.LocalizedString{En: ptr("Vacuum")}}}
+
+	for _, product := range []entities.Product{laptop, tablet, bag, vacuum} {
+		index.Add(product.ID.Hex(), recommendationService.BuildProductVector(product))
+	}
+
+	candidates := index.Candidates(recommendationService.BuildProductVector(laptop))
+
+	// tablet shares the category, the bag shares the "laptop" token, the vacuum shares nothing
+	assert.ElementsMatch(t, []string{laptop.ID.Hex(), tablet.ID.Hex(), bag.ID.Hex()}, candidates)
+
+	index.Remove(tablet.ID.Hex())
+
+	candidates = index.Candidates(recommendationService.BuildProductVector(laptop))
+	assert.NotContains(t, candidates, tablet.ID.Hex())
+}
+
+// benchmarkCatalog generates a synthetic catalog with a skewed vocabulary, similar to a real store
+func benchmarkCatalog(size int) []*entities.Product {
+	random := rand.New(rand.NewSource(42))
+
+	vocabulary := make([]string, 5000)
+	for i := range vocabulary {
+		vocabulary[i] = fmt.Sprintf("term%d", i)
+	}
+
+	products := make([]*entities.Product, size)
+
+	for i := range products {
+		words := make([]string, 20)
+		for j := range words {
+			// squaring skews the distribution towards the first terms of the vocabulary
+			position := random.Float64()
+			words[j] = vocabulary[int(position*position*float64(len(vocabulary)))]
+		}
+
+		products[i] = &entities.Product{
+			ID:          primitive.NewObjectID(),
+			Categories:  []string{fmt.Sprintf("category%d", random.Intn(200))},
+			ClickCount:  random.Intn(1000),
+			SoldCount:   random.Intn(100),
+			Name:        entities.Name{LocalizedString: entities.LocalizedString{En: ptr(strings.Join(words[:3], " "))}},
+			Description: entities.Description{LocalizedString: entities.LocalizedString{En: ptr(strings.Join(words[3:], " "))}},
+		}
+	}
+
+	return products
+}
+
+func BenchmarkRecommendSimilarProducts_LinearScan(b *testing.B) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	catalog := benchmarkCatalog(10000)
+
+	recommendationService.BuildCorpus(catalog)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		recommendationService.RecommendSimilarProducts(*catalog[i%len(catalog)], catalog, services.DefaultRecommendationOptions())
+	}
+}
+
+func BenchmarkRankCandidates_Precomputed(b *testing.B) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	catalog := benchmarkCatalog(10000)
+
+	recommendationService.BuildCorpus(catalog)
+
+	vectors := make(map[string]map[string]float64, len(catalog))
+	for _, product := range catalog {
+		vectors[product.ID.Hex()] = recommendationService.ExtractFeatureVector(*product)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		target := catalog[i%len(catalog)].ID.Hex()
+		recommendationService.RankCandidates(target, vectors[target], vectors, services.DefaultRecommendationOptions())
+	}
+}
+
+func BenchmarkRankCandidates_InvertedIndex(b *testing.B) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	index := services.NewInvertedIndex()
+	catalog := benchmarkCatalog(10000)
+
+	recommendationService.BuildCorpus(catalog)
+
+	raw := make(map[string]*entities.ProductVector, len(catalog))
+	vectors := make(map[string]map[string]float64, len(catalog))
+	for _, product := range catalog {
+		id := product.ID.Hex()
+		raw[id] = recommendationService.BuildProductVector(*product)
+		vectors[id] = recommendationService.Vectorize(raw[id])
+		index.Add(id, raw[id])
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		target := catalog[i%len(catalog)].ID.Hex()
+
+		candidates := make(map[string]map[string]float64)
+		for _, id := range index.Candidates(raw[target]) {
+			candidates[id] = vectors[id]
+		}
+
+		recommendationService.RankCandidates(target, vectors[target], candidates, services.DefaultRecommendationOptions())
+	}
+}
+
 func ptr(s string) *string {
 	return &s
 }
\ No newline at end of file
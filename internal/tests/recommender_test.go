@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/adapters/web/handlers"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"backend-challenge/internal/infrastructure/config"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecayedScores(t *testing.T) {
+	now := time.Now()
+	halfLife := 24 * time.Hour
+
+	scores := services.DecayedScores([]*entities.Event{
+		{ProductID: "a", Type: entities.EventView, Timestamp: now},
+		{ProductID: "a", Type: entities.EventView, Timestamp: now.Add(-24 * time.Hour)},
+		{ProductID: "b", Type: entities.EventPurchase, Quantity: 2, Timestamp: now.Add(-48 * time.Hour)},
+		{ProductID: "c", Type: entities.EventAddToCart, Timestamp: now.Add(time.Hour)}, // clock skew counts as now
+	}, halfLife, now)
+
+	assert.InDelta(t, 1.5, scores["a"], 1e-9, "a view from one half life ago counts half")
+	assert.InDelta(t, 2.5, scores["b"], 1e-9, "purchases count per unit, decayed twice")
+	assert.InDelta(t, 3, scores["c"], 1e-9)
+}
+
+// recordingEventRepository counts the events popularity reads from the wrapped repository
+type recordingEventRepository struct {
+	repositories.EventRepository
+	read int
+}
+
+func (r *recordingEventRepository) GetSince(since time.Time) ([]*entities.Event, error) {
+	events, err := r.EventRepository.GetSince(since)
+	r.read += len(events)
+	return events, err
+}
+
+func (r *recordingEventRepository) GetReceivedSince(since time.Time) ([]*entities.Event, error) {
+	events, err := r.EventRepository.GetReceivedSince(since)
+	r.read += len(events)
+	return events, err
+}
+
+func TestPopularityService_IncrementalRefresh(t *testing.T) {
+	now := time.Now()
+
+	events := &recordingEventRepository{EventRepository: memory.NewEventRepository([]*entities.Event{
+		{ProductID: "a", Type: entities.EventView, Timestamp: now.Add(-24 * time.Hour), ReceivedAt: now.Add(-24 * time.Hour)},
+		{ProductID: "b", Type: entities.EventPurchase, Timestamp: now.Add(-48 * time.Hour), ReceivedAt: now.Add(-48 * time.Hour)},
+		{ProductID: "c", Type: entities.EventClick, Timestamp: now.Add(-30 * 24 * time.Hour), ReceivedAt: now.Add(-30 * 24 * time.Hour)}, // past the horizon
+	})}
+
+	popularity := services.NewPopularityService(events, services.PopularityConfig{HalfLifeHours: 24, RefreshSeconds: 60})
+	require.NoError(t, popularity.Refresh())
+	assert.Equal(t, 2, events.read)
+
+	received := time.Now()
+	_, err := events.InsertMany([]*entities.Event{
+		{ProductID: "a", Type: entities.EventClick, Timestamp: received, ReceivedAt: received},
+		{ProductID: "c", Type: entities.EventAddToCart, Timestamp: received, ReceivedAt: received},
+		// Retried by the client a day late
+		{ProductID: "b", Type: entities.EventPurchase, Timestamp: received.Add(-24 * time.Hour), ReceivedAt: received},
+	})
+	require.NoError(t, err)
+	require.NoError(t, popularity.Refresh())
+	assert.Equal(t, 5, events.read, "a refresh only reads the events ingested since the previous one")
+
+	// Events ingested just before a refresh are read again in case others were still being stored, but counted once
+	require.NoError(t, popularity.Refresh())
+
+	scores, err := popularity.Scores()
+	require.NoError(t, err)
+
+	assert.InDelta(t, 1.5, scores["a"], 1e-3, "older scores decay as if read again")
+	assert.InDelta(t, 3.75, scores["b"], 1e-3, "late events are counted, decayed from their own timestamp")
+	assert.InDelta(t, 3, scores["c"], 1e-3)
+}
+
+func TestRecommendationConfig_Validate(t *testing.T) {
+	assert.NoError(t, services.DefaultRecommendationConfig().Validate())
+	assert.NoError(t, services.RecommendationConfig{Weights: map[string]float64{services.GroupPrice: 0}}.Validate(), "groups can be turned off")
+
+	zero := map[string]float64{}
+	for group := range services.DefaultRecommendationConfig().Weights {
+		zero[group] = 0
+	}
+
+	for _, weights := range []map[string]float64{
+		{services.GroupCategory: -1},
+		{services.GroupName: math.NaN()},
+		zero,
+	} {
+		assert.Error(t, services.RecommendationConfig{Weights: weights}.Validate(), "expected an error for %v", weights)
+	}
+}
+
+func TestPopularityConfig_Validate(t *testing.T) {
+	assert.NoError(t, services.DefaultPopularityConfig().Validate())
+
+	for _, halfLife := range []float64{0, -24} {
+		assert.Error(t, services.PopularityConfig{HalfLifeHours: halfLife}.Validate(), "a half life of %v hours can't decay", halfLife)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"popularity": {"halfLifeHours": 0}}`), 0o600))
+
+	_, err := config.Load(path)
+	assert.Error(t, err, "loading should reject the config")
+}
+
+func TestGetRecommendations_Strategies(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	flashlight, headlamp, batteries := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Linterna frontal", "Linternas"), newTestProduct("Pilas AA", "Baterias")
+
+	for _, p := range []*entities.Product{flashlight, headlamp, batteries} {
+		require.NoError(t, productService.CreateProduct(p))
+	}
+
+	require.NoError(t, itemSimilarityRepo.ReplaceAll([]*entities.ItemSimilarity{{
+		ProductID: flashlight.ID.Hex(),
+		Neighbors: []entities.ItemNeighbor{{ProductID: batteries.ID.Hex(), Score: 0.4, CoOccurrences: 4}},
+	}}))
+
+	opts := services.DefaultRecommendationOptions()
+
+	result, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, services.StrategyContent, result.Source)
+	require.Len(t, result.Recommendations, 1, "content alone doesn't relate flashlights and batteries")
+	assert.Equal(t, headlamp.ID, result.Recommendations[0].Product.ID)
+
+	opts.Strategy = services.StrategyHybrid
+	opts.Explain = true
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, services.StrategyHybrid, result.Source)
+	require.Len(t, result.Recommendations, 2, "batteries bought together with the flashlight should be blended in")
+
+	for _, recommendation := range result.Recommendations {
+		if recommendation.Product.ID == batteries.ID {
+			assert.Equal(t, "co_purchase", recommendation.Explanation[0].Feature)
+			assert.Equal(t, services.GroupBehavior, recommendation.Explanation[0].Group)
+		}
+	}
+
+	opts.Strategy = "random"
+
+	_, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+	assert.IsType(t, &services.UnknownStrategyError{}, err)
+}
+
+// countingSimilarityRepository counts the co-purchase lookups of the wrapped repository
+type countingSimilarityRepository struct {
+	repositories.ItemSimilarityRepository
+	lookups int
+}
+
+func (r *countingSimilarityRepository) GetByProductID(productID string) (*entities.ItemSimilarity, error) {
+	r.lookups++
+	return r.ItemSimilarityRepository.GetByProductID(productID)
+}
+
+func TestHybridRecommender_Explain(t *testing.T) {
+	flashlight, headlamp, batteries := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Linterna frontal", "Linternas"), newTestProduct("Pilas AA", "Baterias")
+
+	similarities := &countingSimilarityRepository{ItemSimilarityRepository: memory.NewItemSimilarityRepository()}
+	require.NoError(t, similarities.ReplaceAll([]*entities.ItemSimilarity{{
+		ProductID: flashlight.ID.Hex(),
+		Neighbors: []entities.ItemNeighbor{{ProductID: batteries.ID.Hex(), Score: 0.4, CoOccurrences: 4}},
+	}}))
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, headlamp, batteries})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	require.NoError(t, vectors.Load())
+
+	recommender := services.NewHybridRecommender(vectors, scorer, similarities,
+		services.NewPopularityService(memory.NewEventRepository(nil), services.DefaultPopularityConfig()), services.DefaultHybridConfig())
+
+	explanations := recommender.(services.Explainer).Explain(flashlight.ID.Hex(), []string{headlamp.ID.Hex(), batteries.ID.Hex()})
+
+	assert.Equal(t, 1, similarities.lookups, "co-purchases are looked up once for every explained candidate")
+	require.NotEmpty(t, explanations[batteries.ID.Hex()])
+	assert.Equal(t, "co_purchase", explanations[batteries.ID.Hex()][0].Feature)
+	require.NotEmpty(t, explanations[headlamp.ID.Hex()])
+	assert.Equal(t, services.GroupCategory, explanations[headlamp.ID.Hex()][0].Group)
+}
+
+func TestGetRecommendationsRoute_InvalidStrategy(t *testing.T) {
+	router := gin.Default()
+	router.GET("/products/:id/recommendations", GetProductHandler().GetRecommendations)
+
+	req, _ := http.NewRequest("GET", "/products/"+primitive.NewObjectID().Hex()+"/recommendations?strategy=random", nil)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "strategy must be one of content, hybrid")
+}
+
+func TestGetRecommendationsRoute_UnknownProduct(t *testing.T) {
+	flashlight := newTestProduct("Linterna táctica", "Linternas")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight})
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	productService := services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+	}, services.NewBrainService(15), services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer))
+
+	experiments := services.NewExperimentService(services.DefaultExperimentConfig(), memory.NewImpressionRepository(), memory.NewEventRepository(nil))
+
+	router := gin.Default()
+	router.GET("/products/:id/recommendations", handlers.NewProductHandler(productService, services.NewBrainService(15), experiments).GetRecommendations)
+
+	for path, status := range map[string]int{
+		"/products/" + flashlight.ID.Hex() + "/recommendations?strategy=content":           http.StatusOK,
+		"/products/" + primitive.NewObjectID().Hex() + "/recommendations?strategy=content": http.StatusNotFound,
+		"/products/not-an-id/recommendations?strategy=content":                             http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, status, resp.Code, "expected status code %d for %s", status, path)
+	}
+}
+
+func TestVectorStore_WritesKeepOtherWeightedVectors(t *testing.T) {
+	flashlight, lantern := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Farol solar", "Faroles")
+
+	productRepo := memory.NewProductRepository([]*entities.Product{flashlight, lantern})
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), services.NewRecommendationService(services.DefaultRecommendationConfig()))
+	require.NoError(t, vectors.Load())
+
+	cached, _ := vectors.Get(lantern.ID.Hex())
+
+	// Requests keep reading while products are written
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				vectors.Vectors(vectors.IDs())
+			}
+		}()
+	}
+
+	headlamp := newTestProduct("Linterna frontal", "Linternas")
+	require.NoError(t, productRepo.Create(headlamp))
+	require.NoError(t, vectors.Upsert(headlamp))
+
+	flashlight.Categories = []string{"Faroles"}
+	require.NoError(t, vectors.Upsert(flashlight))
+
+	wg.Wait()
+
+	unchanged, _ := vectors.Get(lantern.ID.Hex())
+	assert.Equal(t, cached, unchanged, "other products keep their weighted vectors until the next refresh")
+
+	written, ok := vectors.Get(headlamp.ID.Hex())
+	require.True(t, ok)
+	assert.Contains(t, written, "category_linternas")
+
+	written, _ = vectors.Get(flashlight.ID.Hex())
+	assert.Contains(t, written, "category_faroles", "written products are weighted again right away")
+	assert.NotContains(t, written, "category_linternas")
+}
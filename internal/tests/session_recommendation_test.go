@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSessionRecommendations(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	flashlight, headlamp, lantern := newTestProduct("Linterna táctica", "Linternas"), newTestProduct("Linterna frontal", "Linternas"), newTestProduct("Linterna recargable", "Linternas")
+	tent, bigTent := newTestProduct("Carpa iglú", "Carpas"), newTestProduct("Carpa familiar", "Carpas")
+
+	for _, p := range []*entities.Product{flashlight, headlamp, lantern, tent, bigTent} {
+		require.NoError(t, productService.CreateProduct(p))
+	}
+
+	sessionService := services.NewSessionRecommendationService(eventRepo, productRepo, vectorStore, recommendationService)
+
+	result, err := sessionService.GetSessionRecommendations("unknown", services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.Equal(t, services.SourceSession, result.Source)
+	assert.Empty(t, result.Recommendations, "a session without interactions has nothing to go on")
+
+	now := time.Now()
+
+	_, err = eventService.RecordEvents([]*entities.Event{
+		{ProductID: tent.ID.Hex(), Type: entities.EventView, SessionID: "s1", Timestamp: now.Add(-time.Hour)},
+		{ProductID: flashlight.ID.Hex(), Type: entities.EventView, SessionID: "s1", Timestamp: now.Add(-time.Minute)},
+		{ProductID: headlamp.ID.Hex(), Type: entities.EventAddToCart, SessionID: "s1", Timestamp: now},
+		{ProductID: bigTent.ID.Hex(), Type: entities.EventView, SessionID: "s2", Timestamp: now},
+	})
+	require.NoError(t, err)
+
+	result, err = sessionService.GetSessionRecommendations("s1", services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	require.Len(t, result.Recommendations, 2, "products the session already interacted with aren't recommended")
+	assert.Equal(t, lantern.ID, result.Recommendations[0].Product.ID, "the flashlights the session focused on lately should weigh most")
+	assert.Equal(t, bigTent.ID, result.Recommendations[1].Product.ID)
+}
+
+func TestGetSessionRecommendationsRoute_InvalidOptions(t *testing.T) {
+	router := gin.Default()
+
+	router.GET("/recommendations", recommendationHandler.GetSessionRecommendations)
+
+	for _, query := range []string{"", "limit=5", "sessionId=s1&limit=0", "sessionId=s1&strategy=random"} {
+		req, _ := http.NewRequest("GET", "/recommendations?"+query, nil)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "expected status code 400 for %s", query)
+		assert.Contains(t, resp.Body.String(), "error")
+	}
+}
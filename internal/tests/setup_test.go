@@ -5,12 +5,14 @@ import (
 	"backend-challenge/internal/adapters/persistence/repository"
 	"backend-challenge/internal/adapters/web/handlers"
 	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
 	"backend-challenge/internal/domain/repositories"
 	"context"
 	"fmt"
 	"os"
 	"testing"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -20,11 +22,21 @@ var (
 	testClient      *mongo.Client
 	categoryRepo    repositories.CategoryRepository
 	productRepo     repositories.ProductRepository
+	eventRepo       repositories.EventRepository
+	itemSimilarityRepo repositories.ItemSimilarityRepository
 	recommendationService *services.RecommendationService
+	vectorStore     *services.VectorStore
+	brainService    *services.BrainService
+	popularityService *services.PopularityService
 	categoryService services.CategoryService
 	productService  services.ProductService
+	eventService    services.EventService
+	experimentService *services.ExperimentService
 	categoryHandler *handlers.CategoryHandler
 	productHandler  *handlers.ProductHandler
+	eventHandler    *handlers.EventHandler
+	trendingHandler *handlers.TrendingHandler
+	recommendationHandler *handlers.RecommendationHandler
 )
 
 // TestMain is the main entry point for tests in this package
@@ -50,13 +62,32 @@ func TestMain(m *testing.M) {
 	// Initialize repositories and services
 	categoryRepo = repository.NewCategoryRepository(testClient, "backend-challenge-test", "categories")
 	productRepo = repository.NewProductRepository(testClient, "backend-challenge-test", "products")
-	recommendationService = services.NewRecommendationService()
-	categoryService = services.NewCategoryService(categoryRepo)
-	productService = services.NewProductService(productRepo, recommendationService)
+	productVectorRepo := repository.NewProductVectorRepository(testClient, "backend-challenge-test", "product_vectors")
+	eventRepo = repository.NewEventRepository(testClient, "backend-challenge-test", "events")
+	itemSimilarityRepo = repository.NewItemSimilarityRepository(testClient, "backend-challenge-test", "item_similarities")
+	recommendationService = services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectorStore = services.NewVectorStore(productRepo, productVectorRepo, recommendationService)
+	brainService = services.NewBrainService(15)
+	categoryService = services.NewCategoryService(categoryRepo, recommendationService)
+	popularityService = services.NewPopularityService(eventRepo, services.DefaultPopularityConfig())
+	embeddingIndex := services.NewEmbeddingIndex(repository.NewTermEmbeddingRepository(testClient, "backend-challenge-test", "term_embeddings"), vectorStore, services.DefaultEmbeddingConfig())
+	productService = services.NewProductService(productRepo, itemSimilarityRepo, vectorStore, map[string]services.Recommender{
+		services.StrategyContent:   services.NewContentRecommender(vectorStore, recommendationService),
+		services.StrategyHybrid:    services.NewHybridRecommender(vectorStore, recommendationService, itemSimilarityRepo, popularityService, services.DefaultHybridConfig()),
+		services.StrategyEmbedding: services.NewEmbeddingRecommender(embeddingIndex, vectorStore, recommendationService),
+	}, brainService, services.NewMerchandisingService(repository.NewMerchandisingRuleRepository(testClient, "backend-challenge-test", "merchandising_rules"), vectorStore, recommendationService))
+	experimentService = services.NewExperimentService(services.DefaultExperimentConfig(), repository.NewImpressionRepository(testClient, "backend-challenge-test", "impressions"), eventRepo)
+	eventService = services.NewEventService(eventRepo, productRepo, vectorStore, experimentService)
 
 	// Initialize handlers
 	categoryHandler = handlers.NewCategoryHandler(categoryService)
-	productHandler = handlers.NewProductHandler(productService /*brainService*/)
+	productHandler = handlers.NewProductHandler(productService, brainService, experimentService)
+	eventHandler = handlers.NewEventHandler(eventService)
+	trendingHandler = handlers.NewTrendingHandler(services.NewTrendingService(productRepo, popularityService))
+	recommendationHandler = handlers.NewRecommendationHandler(
+		services.NewSessionRecommendationService(eventRepo, productRepo, vectorStore, recommendationService),
+		services.NewBatchRecommendationService(productRepo, vectorStore, recommendationService),
+	)
 
 	// Run tests
 	exitCode := m.Run()
@@ -134,4 +165,21 @@ func GetRecommendationService() *services.RecommendationService {
 		panic("Category repository not initialized")
 	}
 	return recommendationService
-}
\ No newline at end of file
+}
+
+func GetEventHandler() *handlers.EventHandler {
+	if eventHandler == nil {
+		panic("Event handler not initialized")
+	}
+	return eventHandler
+}
+
+// newTestProduct builds a published product with a Spanish name, in the given categories
+func newTestProduct(name string, categories ...string) *entities.Product {
+	return &entities.Product{
+		ID:         primitive.NewObjectID(),
+		Categories: categories,
+		Name:       entities.Name{LocalizedString: entities.LocalizedString{Es: ptr(name)}},
+		Published:  true,
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Descriptions below are taken from products.json
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "entities, tags and emoji",
+			input:    "<p>Pantalla Led Frontal C.O.B 🌟, un compa&ntilde;ero ideal para iluminar cada momento. Con sus 25mm x 25mm y 30 micro leds, &iexcl;la oscuridad ser&aacute; cosa del pasado!</p>",
+			expected: "pantalla led frontal c.o.b , un companero ideal para iluminar cada momento. con sus 25mm x 25mm y 30 micro leds, ¡la oscuridad sera cosa del pasado!",
+		},
+		{
+			name:     "list items and inverted question marks",
+			input:    "<ul>\r\n\t<li>&iquest;Busc&aacute;s comodidad? Utiliz&aacute; su Gancho para Colgar o el Soporte Rebatible para apoyarlo en el &aacute;ngulo que necesit&eacute;s. 🆒</li>",
+			expected: "¿buscas comodidad? utiliza su gancho para colgar o el soporte rebatible para apoyarlo en el angulo que necesites.",
+		},
+		{
+			name:     "non breaking spaces and line breaks",
+			input:    "<p>Balines de Precisi&oacute;n 4.5mm para pistolas de Co2<br />\n1500&nbsp;unidades<br />\n&nbsp;</p>\n",
+			expected: "balines de precision 4.5mm para pistolas de co2 1500 unidades",
+		},
+		{
+			name:     "tags with quoted attributes",
+			input:    `<p><span style="color: rgb(102, 102, 102); font-family: &quot;Proxima Nova&quot;, -apple-system">Guantes primera piel</span></p>`,
+			expected: "guantes primera piel",
+		},
+		{
+			name:     "composed emoji",
+			input:    "<p>*La imagen es ilustrativa, pero la experiencia es real. 😉👌 🛠️ 💪🏽</p>",
+			expected: "*la imagen es ilustrativa, pero la experiencia es real.",
+		},
+		{
+			name:     "accented name",
+			input:    "par de atributos metálicos oro/plata",
+			expected: "par de atributos metalicos oro/plata",
+		},
+		{
+			name:     "empty description",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.NormalizeText(tt.input))
+		})
+	}
+}
+
+func TestExtractFeatureVector_IgnoresMarkup(t *testing.T) {
+	recommendationService := services.NewRecommendationService(services.DefaultRecommendationConfig())
+
+	product := entities.Product{
+		ID: primitive.NewObjectID(),
+		Description: entities.Description{LocalizedString: entities.LocalizedString{
+			Es: ptr("<p>Un compa&ntilde;ero ideal 🌟</p>\r\n\r\n<p>&nbsp;</p>\r\n<ul>\r\n\t<li>Bater&iacute;a Recargable</li>\r\n</ul>"),
+		}},
+	}
+
+	vector := recommendationService.ExtractFeatureVector(product)
+
+	for _, garbage := range []string{"description_p", "description_ntild", "description_nbsp", "description_li", "description_ul", "description_iacut", "description_🌟"} {
+		assert.NotContains(t, vector, garbage)
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTrending(t *testing.T) {
+	cleanup := setupTest(t)
+	defer cleanup()
+
+	product := func(category, store string) *entities.Product {
+		product := newTestProduct("Producto", category)
+		product.StoreID = store
+		return product
+	}
+
+	oldHit, newHit, otherStore, quiet := product("Linternas", "s1"), product("Linternas", "s1"), product("Linternas", "s2"), product("Carpas", "s1")
+
+	for _, p := range []*entities.Product{oldHit, newHit, otherStore, quiet} {
+		require.NoError(t, productRepo.Create(p))
+	}
+
+	now := time.Now()
+	events := []*entities.Event{}
+
+	// Many sales five days ago weigh less than a few this morning
+	for i := 0; i < 20; i++ {
+		events = append(events, &entities.Event{ProductID: oldHit.ID.Hex(), Type: entities.EventPurchase, Timestamp: now.Add(-5 * 24 * time.Hour)})
+	}
+	for i := 0; i < 3; i++ {
+		events = append(events, &entities.Event{ProductID: newHit.ID.Hex(), Type: entities.EventPurchase, Timestamp: now.Add(-2 * time.Hour)})
+	}
+	events = append(events, &entities.Event{ProductID: otherStore.ID.Hex(), Type: entities.EventView, Timestamp: now})
+
+	_, err := eventService.RecordEvents(events)
+	require.NoError(t, err)
+
+	trendingService := services.NewTrendingService(productRepo, services.NewPopularityService(eventRepo, services.PopularityConfig{HalfLifeHours: 24, RefreshSeconds: 60}))
+
+	result, err := trendingService.GetTrending(services.TrendingOptions{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, result.Products, 3, "products without recent interactions aren't trending")
+	assert.Equal(t, newHit.ID, result.Products[0].Product.ID)
+	assert.Equal(t, oldHit.ID, result.Products[1].Product.ID)
+	assert.Equal(t, otherStore.ID, result.Products[2].Product.ID)
+
+	result, err = trendingService.GetTrending(services.TrendingOptions{Category: "linternas", StoreID: "s1", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, result.Products, 2)
+	assert.Equal(t, newHit.ID, result.Products[0].Product.ID)
+
+	result, err = trendingService.GetTrending(services.TrendingOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, oldHit.ID, result.Products[0].Product.ID)
+}
+
+func TestGetTrendingRoute_InvalidOptions(t *testing.T) {
+	router := gin.Default()
+
+	router.GET("/products/trending", trendingHandler.GetTrending)
+	router.GET("/products/:id", GetProductHandler().GetProductByID)
+
+	for _, query := range []string{"limit=0", "limit=100", "offset=-1"} {
+		req, _ := http.NewRequest("GET", "/products/trending?"+query, nil)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "expected status code 400 for %s", query)
+		assert.Contains(t, resp.Body.String(), "error")
+	}
+}
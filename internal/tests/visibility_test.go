@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"backend-challenge/internal/adapters/persistence/memory"
+	"backend-challenge/internal/adapters/web/handlers"
+	"backend-challenge/internal/application/services"
+	"backend-challenge/internal/domain/entities"
+	"backend-challenge/internal/domain/repositories"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// visibilityTestCatalog is a catalog of flashlights, one of them not published yet
+func visibilityTestCatalog() (products []*entities.Product, draft *entities.Product) {
+	product := func(name string, published bool) *entities.Product {
+		product := newTestProduct(name, "Linternas")
+		product.Published = published
+		return product
+	}
+
+	draft = product("Linterna táctica recargable", false)
+
+	return []*entities.Product{product("Linterna táctica", true), product("Linterna frontal", true), draft}, draft
+}
+
+func newVisibilityTestService(products []*entities.Product) (services.ProductService, services.MerchandisingService) {
+	productRepo := memory.NewProductRepository(products)
+	scorer := services.NewRecommendationService(services.DefaultRecommendationConfig())
+	vectors := services.NewVectorStore(productRepo, memory.NewProductVectorRepository(), scorer)
+	merchandising := services.NewMerchandisingService(memory.NewMerchandisingRuleRepository(), vectors, scorer)
+
+	return services.NewProductService(productRepo, memory.NewItemSimilarityRepository(), vectors, map[string]services.Recommender{
+		services.StrategyContent: services.NewContentRecommender(vectors, scorer),
+		services.StrategyHybrid: services.NewHybridRecommender(vectors, scorer, memory.NewItemSimilarityRepository(),
+			services.NewPopularityService(memory.NewEventRepository(nil), services.DefaultPopularityConfig()), services.DefaultHybridConfig()),
+	}, services.NewBrainService(15), merchandising), merchandising
+}
+
+func TestUnpublishedProducts(t *testing.T) {
+	products, draft := visibilityTestCatalog()
+	flashlight, headlamp := products[0], products[1]
+
+	productService, merchandising := newVisibilityTestService(products)
+
+	ids := func(recommendations []*services.Recommendation) []primitive.ObjectID {
+		ids := []primitive.ObjectID{}
+		for _, recommendation := range recommendations {
+			ids = append(ids, recommendation.Product.ID)
+		}
+		return ids
+	}
+
+	for _, strategy := range []string{services.StrategyContent, services.StrategyHybrid} {
+		opts := services.DefaultRecommendationOptions()
+		opts.Strategy = strategy
+
+		result, err := productService.GetRecommendations(flashlight.ID.Hex(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []primitive.ObjectID{headlamp.ID}, ids(result.Recommendations), "%s recommends published products only", strategy)
+
+		opts.IncludeUnpublished = true
+
+		result, err = productService.GetRecommendations(flashlight.ID.Hex(), opts)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []primitive.ObjectID{headlamp.ID, draft.ID}, ids(result.Recommendations))
+	}
+
+	// An unpublished product still gets recommendations of its own
+	result, err := productService.GetRecommendations(draft.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []primitive.ObjectID{flashlight.ID, headlamp.ID}, ids(result.Recommendations))
+
+	// Nor can merchandising pin it
+	require.NoError(t, merchandising.CreateRule(&entities.MerchandisingRule{Name: "launch", Action: entities.MerchandisingPin,
+		Target: entities.MerchandisingTarget{ProductIDs: []string{draft.ID.Hex()}}}))
+
+	result, err = productService.GetRecommendations(flashlight.ID.Hex(), services.DefaultRecommendationOptions())
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{headlamp.ID}, ids(result.Recommendations))
+	assert.Empty(t, result.Merchandising)
+
+	listed, err := productService.GetAllProducts(repositories.ProductQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []*entities.Product{flashlight, headlamp}, listed)
+
+	listed, err = productService.GetAllProducts(repositories.ProductQuery{IncludeUnpublished: true})
+	require.NoError(t, err)
+	assert.Len(t, listed, 3)
+
+	page, err := productService.GetPaginatedProducts(1, 10, repositories.ProductQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []*entities.Product{headlamp}, page, "pages are counted over the published products")
+}
+
+func TestUnpublishedProductsRoute(t *testing.T) {
+	products, draft := visibilityTestCatalog()
+	productService, _ := newVisibilityTestService(products)
+
+	experiments := services.NewExperimentService(services.DefaultExperimentConfig(), memory.NewImpressionRepository(), memory.NewEventRepository(nil))
+	productHandler := handlers.NewProductHandler(productService, services.NewBrainService(15), experiments)
+
+	router := gin.Default()
+	router.Use(handlers.AdminScope("secret"))
+	router.GET("/products", productHandler.GetAllProducts)
+	router.GET("/products/:id", productHandler.GetProductByID)
+	router.PUT("/products/:id", productHandler.UpdateProduct)
+	router.GET("/products/:id/recommendations", productHandler.GetRecommendations)
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+
+		if token != "" {
+			req.Header.Set(handlers.AdminTokenHeader, token)
+		}
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	request := func(path, token string) *httptest.ResponseRecorder {
+		return send("GET", path, token, "")
+	}
+
+	listed := func(resp *httptest.ResponseRecorder) []*entities.Product {
+		var body struct {
+			Products []*entities.Product `json:"products"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body.Products
+	}
+
+	resp := request("/products", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, listed(resp), 2)
+
+	for _, path := range []string{"/products?includeUnpublished=true", "/products/" + products[0].ID.Hex() + "/recommendations?includeUnpublished=true"} {
+		for _, token := range []string{"", "wrong"} {
+			resp = request(path, token)
+			assert.Equal(t, http.StatusForbidden, resp.Code, "expected status code 403 for %s without the admin token", path)
+		}
+	}
+
+	resp = request("/products?includeUnpublished=maybe", "secret")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = request("/products?includeUnpublished=true", "secret")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, listed(resp), 3)
+
+	resp = request("/products/"+products[0].ID.Hex()+"/recommendations?strategy=content&includeUnpublished=true", "secret")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), draft.ID.Hex())
+
+	resp = request("/products/"+draft.ID.Hex(), "")
+	assert.Equal(t, http.StatusNotFound, resp.Code, "unpublished products are hidden")
+
+	resp = request("/products/"+draft.ID.Hex()+"?includeUnpublished=true", "secret")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// Products can be unpublished, and stay so through updates leaving the flag out
+	headlamp := products[1].ID.Hex()
+
+	resp = send("PUT", "/products/"+headlamp, "", `{"name": {"es": "Linterna frontal"}, "published": false}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp = send("PUT", "/products/"+headlamp, "", `{"name": {"es": "Linterna frontal LED"}}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp = request("/products/"+headlamp, "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = request("/products", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Len(t, listed(resp), 1)
+
+	// Without a token configured there is no admin scope
+	router = gin.Default()
+	router.Use(handlers.AdminScope(""))
+	router.GET("/products", productHandler.GetAllProducts)
+
+	resp = request("/products?includeUnpublished=true", "")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}